@@ -2,6 +2,8 @@ package xslog
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"golang.org/x/exp/slog"
 )
@@ -36,3 +38,37 @@ func ContextLogger(ctx context.Context) *slog.Logger {
 func TransferLogger(dst context.Context, src context.Context) context.Context {
 	return WithLogger(dst, ContextLogger(src))
 }
+
+// Detach returns a context that carries the values of ctx, such as the logger, attrs, request ID and event,
+// but is neither canceled nor bounded by ctx, like context.WithoutCancel of Go 1.21.
+// The returned context keeps every value of ctx, and ctx itself, reachable for as long as it is used.
+func Detach(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return detachedContext{parent: ctx}
+}
+
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key any) any {
+	return c.parent.Value(key)
+}
+
+func (c detachedContext) String() string {
+	return fmt.Sprintf("%v.Detach", c.parent)
+}
@@ -0,0 +1,32 @@
+package xslog
+
+import (
+	"context"
+	"sync/atomic"
+
+	"golang.org/x/exp/slog"
+)
+
+var defaultLogger atomic.Pointer[slog.Logger]
+
+// SetDefault makes logger the package-level logger, which is used when a context carries no logger.
+// Passing nil restores the fallback to slog.Default.
+func SetDefault(logger *slog.Logger) {
+	defaultLogger.Store(logger)
+}
+
+// Default returns the package-level logger set with SetDefault, or slog.Default if none was set.
+func Default() *slog.Logger {
+	if logger := defaultLogger.Load(); logger != nil {
+		return logger
+	}
+	return slog.Default()
+}
+
+// Logger returns the logger bound with ctx. If no logger is bound, it returns Default. It never returns nil.
+func Logger(ctx context.Context) *slog.Logger {
+	if logger := ContextLogger(ctx); logger != nil {
+		return logger
+	}
+	return Default()
+}
@@ -0,0 +1,38 @@
+package xslog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/galecore/xslog/util"
+	"github.com/galecore/xslog/xtesting"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+)
+
+func TestDefault(t *testing.T) {
+	t.Cleanup(func() { SetDefault(nil) })
+
+	assert.Equal(t, slog.Default(), Default())
+
+	logger := slog.New(xtesting.NewHandler(util.NewBufferedLogger()))
+	SetDefault(logger)
+	assert.Equal(t, logger, Default())
+
+	SetDefault(nil)
+	assert.Equal(t, slog.Default(), Default())
+}
+
+func TestLogger(t *testing.T) {
+	t.Cleanup(func() { SetDefault(nil) })
+
+	assert.NotNil(t, Logger(nil))
+	assert.Equal(t, slog.Default(), Logger(context.Background()))
+
+	defaultLogger := slog.New(xtesting.NewHandler(util.NewBufferedLogger()))
+	SetDefault(defaultLogger)
+	assert.Equal(t, defaultLogger, Logger(context.Background()))
+
+	ctxLogger := slog.New(xtesting.NewHandler(util.NewBufferedLogger()))
+	assert.Equal(t, ctxLogger, Logger(WithLogger(context.Background(), ctxLogger)))
+}
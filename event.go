@@ -0,0 +1,46 @@
+package xslog
+
+import (
+	"context"
+
+	"github.com/galecore/xslog/xdata"
+	"golang.org/x/exp/slog"
+)
+
+// EventDurationKey is the attr key under which EmitEvent records the duration of the event.
+const EventDurationKey = "duration"
+
+// StartEvent returns a new context that is bound with a new canonical log line event.
+// Attrs, counters and timers added to the context with AddToEvent, CountInEvent and TimeInEvent
+// are logged as one record by EmitEvent.
+func StartEvent(ctx context.Context) context.Context {
+	ctx, _ = xdata.WithEvent(ctx)
+	return ctx
+}
+
+// AddToEvent sets attrs on the event of ctx. It does nothing if ctx has no event.
+func AddToEvent(ctx context.Context, attrs ...slog.Attr) {
+	xdata.ContextEvent(ctx).Add(attrs...)
+}
+
+// CountInEvent adds delta to the counter named key of the event of ctx. It does nothing if ctx has no event.
+func CountInEvent(ctx context.Context, key string, delta int64) {
+	xdata.ContextEvent(ctx).Count(key, delta)
+}
+
+// TimeInEvent starts measuring an operation and returns a function that adds its duration
+// to the timer named key of the event of ctx, e.g. `defer xslog.TimeInEvent(ctx, "db")()`.
+func TimeInEvent(ctx context.Context, key string) (stop func()) {
+	return xdata.ContextEvent(ctx).Time(key)
+}
+
+// EmitEvent logs the event of ctx as one record carrying attrs, the attrs of the event and its duration.
+func EmitEvent(ctx context.Context, leveler slog.Leveler, msg string, attrs ...slog.Attr) {
+	if e := xdata.ContextEvent(ctx); e != nil {
+		eventAttrs := e.Attrs()
+		all := make([]slog.Attr, 0, len(attrs)+len(eventAttrs)+1)
+		all = append(append(all, attrs...), eventAttrs...)
+		attrs = append(all, slog.Duration(EventDurationKey, e.Elapsed()))
+	}
+	log(ctx, leveler.Level(), 0, msg, attrs, nil)
+}
@@ -0,0 +1,66 @@
+package xslog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/galecore/xslog/xtesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+)
+
+func TestEmitEvent(t *testing.T) {
+	r := xtesting.NewRecorder()
+	ctx := StartEvent(newTestContext(r))
+
+	AddToEvent(ctx, slog.String("user", "bob"))
+	group, groupCtx := NewGroup(ctx)
+	for _, name := range []string{"a", "b", "c"} {
+		group.Go(name, func(ctx context.Context) error {
+			defer TimeInEvent(ctx, "fetch")()
+			CountInEvent(ctx, "fetches", 1)
+			return nil
+		})
+	}
+	require.NoError(t, group.Wait())
+	Info(groupCtx, "not part of the event")
+	EmitEvent(ctx, slog.LevelInfo, "request", slog.String("route", "/users"))
+
+	records := r.Records()
+	require.Len(t, records, 2)
+	assert.Equal(t, "INFO: not part of the event [request_id=abc]", records[0].String())
+	xtesting.RequireLogged(t, r,
+		xtesting.MessageMatches("^request$"),
+		xtesting.AttrEqual("route", "/users"),
+		xtesting.AttrEqual("user", "bob"),
+		xtesting.AttrEqual("fetches", 3),
+		xtesting.HasAttr("fetch"),
+		xtesting.HasAttr("duration"),
+		xtesting.AttrEqual("request_id", "abc"),
+	)
+}
+
+func TestEmitEvent_WithoutEvent(t *testing.T) {
+	r := xtesting.NewRecorder()
+	ctx := newTestContext(r)
+
+	AddToEvent(ctx, slog.String("user", "bob"))
+	CountInEvent(ctx, "n", 1)
+	TimeInEvent(ctx, "t")()
+	EmitEvent(ctx, slog.LevelInfo, "request", slog.String("route", "/users"))
+
+	records := r.Records()
+	require.Len(t, records, 1)
+	assert.Equal(t, "INFO: request [route=/users request_id=abc]", records[0].String())
+}
+
+func TestEmitEvent_KeepsCallerAttrs(t *testing.T) {
+	ctx := StartEvent(newTestContext(xtesting.NewRecorder()))
+	AddToEvent(ctx, slog.String("user", "bob"))
+
+	attrs := make([]slog.Attr, 1, 4)
+	attrs[0] = slog.String("route", "/users")
+	EmitEvent(ctx, slog.LevelInfo, "request", attrs...)
+	assert.Equal(t, []slog.Attr{slog.String("route", "/users"), {}, {}, {}}, attrs[:4])
+}
@@ -0,0 +1,138 @@
+package xslog
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"golang.org/x/exp/slog"
+)
+
+// Go runs fn in a new goroutine named name. The goroutine gets a context that carries the values of ctx,
+// such as the logger and attrs, but is neither canceled nor bounded by ctx, so it can outlive the caller.
+// All values of ctx are kept, including credentials and trace spans, and stay reachable until fn returns;
+// pass a fresh context with TransferLogger and xdata.TransferAttrs to keep only the logger and attrs.
+// Panics in fn are logged as Error records and recovered.
+func Go(ctx context.Context, name string, fn func(ctx context.Context)) {
+	goNamed(ctx, name, fn, false)
+}
+
+// GoRepanic is like Go, but raises panics in fn again after they were logged, which crashes the program.
+func GoRepanic(ctx context.Context, name string, fn func(ctx context.Context)) {
+	goNamed(ctx, name, fn, true)
+}
+
+func goNamed(ctx context.Context, name string, fn func(ctx context.Context), repanic bool) {
+	child := Detach(ctx)
+	go func() {
+		defer Recover(child, name, repanic)
+		fn(child)
+	}()
+}
+
+// Recover logs a panic of the goroutine named name as an Error record with the panic value and stack.
+// If repanic is set, the panic is raised again after it was logged.
+// Recover must be called directly by a deferred function call.
+func Recover(ctx context.Context, name string, repanic bool) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	logPanic(ctx, name, r, debug.Stack())
+	if repanic {
+		panic(r)
+	}
+}
+
+// PanicError is returned by Group.Wait when a goroutine of the group panicked.
+type PanicError struct {
+	Name  string
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("goroutine %q panicked: %v", e.Name, e.Value)
+}
+
+// Group is a collection of named goroutines working on subtasks of a common task, like errgroup.Group.
+// Goroutines receive the group context, which carries the logger and attrs of the parent context.
+// Panics are logged as Error records and turned into a PanicError returned by Wait.
+type Group struct {
+	// Repanic makes a panicking goroutine raise its panic again after it was logged,
+	// instead of turning it into an error. It must be set before the first call to Go.
+	Repanic bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	errOnce sync.Once
+	err     error
+}
+
+// NewGroup returns a new Group and an associated context derived from ctx.
+// The derived context is canceled the first time a goroutine returns an error or panics,
+// or the first time Wait returns, whichever occurs first.
+func NewGroup(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{ctx: ctx, cancel: cancel}, ctx
+}
+
+// Go calls fn in a new goroutine named name.
+// The first call to return a non-nil error cancels the group; its error will be returned by Wait.
+func (g *Group) Go(name string, fn func(ctx context.Context) error) {
+	ctx := g.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			stack := debug.Stack()
+			logPanic(ctx, name, r, stack)
+			if g.Repanic {
+				panic(r)
+			}
+			g.setErr(&PanicError{Name: name, Value: r, Stack: stack})
+		}()
+
+		if err := fn(ctx); err != nil {
+			g.setErr(err)
+		}
+	}()
+}
+
+// Wait blocks until all goroutines started with Go have returned,
+// then returns the first non-nil error (if any) from them.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel()
+	}
+	return g.err
+}
+
+func (g *Group) setErr(err error) {
+	g.errOnce.Do(func() {
+		g.err = err
+		if g.cancel != nil {
+			g.cancel()
+		}
+	})
+}
+
+func logPanic(ctx context.Context, name string, r any, stack []byte) {
+	Log(ctx, slog.LevelError, "goroutine panicked", []slog.Attr{
+		slog.String("goroutine", name),
+		slog.Any("panic", r),
+		slog.String("stack", string(stack)),
+	})
+}
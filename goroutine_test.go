@@ -0,0 +1,171 @@
+package xslog
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/galecore/xslog/xdata"
+	"github.com/galecore/xslog/xtesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+)
+
+func newTestContext(h slog.Handler) context.Context {
+	ctx := WithLogger(context.Background(), slog.New(xdata.NewHandler(h)))
+	return xdata.WithAttrs(ctx, slog.String("request_id", "abc"))
+}
+
+// notifyHandler closes handled once it handled its first record.
+type notifyHandler struct {
+	slog.Handler
+	once    *sync.Once
+	handled chan struct{}
+}
+
+func newNotifyHandler(h slog.Handler) notifyHandler {
+	return notifyHandler{Handler: h, once: new(sync.Once), handled: make(chan struct{})}
+}
+
+func (h notifyHandler) Handle(ctx context.Context, r slog.Record) error {
+	defer h.once.Do(func() { close(h.handled) })
+	return h.Handler.Handle(ctx, r)
+}
+
+func TestGo(t *testing.T) {
+	t.Run("transfers logger and attrs", func(t *testing.T) {
+		parent, cancel := context.WithCancel(newTestContext(xtesting.NewRecorder()))
+		cancel()
+
+		done := make(chan context.Context)
+		Go(parent, "worker", func(ctx context.Context) {
+			done <- ctx
+		})
+		ctx := <-done
+		assert.NoError(t, ctx.Err())
+		assert.Equal(t, ContextLogger(parent), ContextLogger(ctx))
+		assert.Equal(t, xdata.ContextAttrs(parent), xdata.ContextAttrs(ctx))
+	})
+
+	t.Run("keeps context values", func(t *testing.T) {
+		clock := xtesting.NewFakeClock(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Second)
+		parent := xdata.WithClock(xdata.WithRequestID(context.Background(), "req-1"), clock)
+		parent, event := xdata.WithEvent(parent)
+		parent, cancel := context.WithTimeout(parent, time.Minute)
+		cancel()
+
+		done := make(chan context.Context)
+		Go(parent, "worker", func(ctx context.Context) {
+			done <- ctx
+		})
+		ctx := <-done
+		_, hasDeadline := ctx.Deadline()
+		assert.False(t, hasDeadline)
+		assert.Nil(t, ctx.Done())
+		assert.Equal(t, "req-1", xdata.RequestID(ctx))
+		assert.Same(t, event, xdata.ContextEvent(ctx))
+		assert.Equal(t, xdata.Clock(clock), xdata.ContextClock(ctx))
+	})
+
+	t.Run("recovers panic", func(t *testing.T) {
+		r := xtesting.NewRecorder()
+		h := newNotifyHandler(r)
+		Go(newTestContext(h), "worker", func(ctx context.Context) {
+			panic("boom")
+		})
+		<-h.handled
+
+		xtesting.RequireCount(t, r, 1)
+		xtesting.RequireLogged(t, r,
+			xtesting.WithLevel(slog.LevelError),
+			xtesting.MessageMatches("^goroutine panicked$"),
+			xtesting.AttrEqual("goroutine", "worker"),
+			xtesting.AttrEqual("panic", "boom"),
+			xtesting.HasAttr("stack"),
+			xtesting.AttrEqual("request_id", "abc"),
+		)
+	})
+}
+
+func TestRecover(t *testing.T) {
+	r := xtesting.NewRecorder()
+	ctx := newTestContext(r)
+	panicked := func(value string) []xtesting.Matcher {
+		return []xtesting.Matcher{
+			xtesting.WithLevel(slog.LevelError),
+			xtesting.MessageMatches("^goroutine panicked$"),
+			xtesting.AttrEqual("goroutine", "worker"),
+			xtesting.AttrEqual("panic", value),
+		}
+	}
+
+	assert.NotPanics(t, func() {
+		defer Recover(ctx, "worker", false)
+		panic("boom")
+	})
+	xtesting.RequireLogged(t, r, panicked("boom")...)
+
+	assert.PanicsWithValue(t, "again", func() {
+		defer Recover(ctx, "worker", true)
+		panic("again")
+	})
+	xtesting.RequireLogged(t, r, panicked("again")...)
+
+	assert.NotPanics(t, func() {
+		defer Recover(ctx, "worker", true)
+	})
+	xtesting.RequireCount(t, r, 2)
+}
+
+func TestGroup(t *testing.T) {
+	t.Run("returns first error", func(t *testing.T) {
+		r := xtesting.NewRecorder()
+		g, ctx := NewGroup(newTestContext(r))
+		errBoom := errors.New("boom")
+
+		g.Go("failing", func(ctx context.Context) error {
+			return errBoom
+		})
+		g.Go("waiting", func(ctx context.Context) error {
+			<-ctx.Done()
+			assert.Equal(t, "abc", xdata.ContextAttrs(ctx)[0].Value.String())
+			return ctx.Err()
+		})
+		assert.Equal(t, errBoom, g.Wait())
+		assert.Error(t, ctx.Err())
+		assert.Empty(t, r.Records())
+	})
+
+	t.Run("recovers panic", func(t *testing.T) {
+		r := xtesting.NewRecorder()
+		g, _ := NewGroup(newTestContext(r))
+		g.Go("worker", func(ctx context.Context) error {
+			panic("boom")
+		})
+
+		err := g.Wait()
+		var panicErr *PanicError
+		require.ErrorAs(t, err, &panicErr)
+		assert.Equal(t, "worker", panicErr.Name)
+		assert.Equal(t, "boom", panicErr.Value)
+		assert.NotEmpty(t, panicErr.Stack)
+		assert.Equal(t, `goroutine "worker" panicked: boom`, err.Error())
+		xtesting.RequireLogged(t, r,
+			xtesting.WithLevel(slog.LevelError),
+			xtesting.AttrEqual("goroutine", "worker"),
+			xtesting.AttrEqual("panic", "boom"),
+			xtesting.AttrEqual("stack", string(panicErr.Stack)),
+		)
+	})
+
+	t.Run("zero value", func(t *testing.T) {
+		var g Group
+		g.Go("worker", func(ctx context.Context) error {
+			return nil
+		})
+		assert.NoError(t, g.Wait())
+	})
+}
@@ -0,0 +1,41 @@
+package xslog
+
+import (
+	"golang.org/x/exp/slog"
+)
+
+// Levels in addition to the ones defined by slog.
+const (
+	LevelTrace  slog.Level = slog.LevelDebug - 4
+	LevelNotice slog.Level = slog.LevelInfo + 2
+	LevelFatal  slog.Level = slog.LevelError + 4
+	LevelPanic  slog.Level = slog.LevelError + 8
+)
+
+var levelNames = map[slog.Level]string{
+	LevelTrace:  "TRACE",
+	LevelNotice: "NOTICE",
+	LevelFatal:  "FATAL",
+	LevelPanic:  "PANIC",
+}
+
+// LevelName returns the name of level, using TRACE, NOTICE, FATAL and PANIC for the levels
+// defined by this package and slog.Level.String for all others.
+func LevelName(level slog.Level) string {
+	if name, ok := levelNames[level]; ok {
+		return name
+	}
+	return level.String()
+}
+
+// ReplaceLevelName is a slog.HandlerOptions.ReplaceAttr function that writes levels with LevelName.
+func ReplaceLevelName(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 || a.Key != slog.LevelKey {
+		return a
+	}
+	level, ok := a.Value.Any().(slog.Level)
+	if !ok {
+		return a
+	}
+	return slog.String(slog.LevelKey, LevelName(level))
+}
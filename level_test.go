@@ -0,0 +1,30 @@
+package xslog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+)
+
+func TestLevelName(t *testing.T) {
+	assert.Equal(t, "TRACE", LevelName(LevelTrace))
+	assert.Equal(t, "DEBUG", LevelName(slog.LevelDebug))
+	assert.Equal(t, "INFO", LevelName(slog.LevelInfo))
+	assert.Equal(t, "NOTICE", LevelName(LevelNotice))
+	assert.Equal(t, "WARN", LevelName(slog.LevelWarn))
+	assert.Equal(t, "ERROR", LevelName(slog.LevelError))
+	assert.Equal(t, "FATAL", LevelName(LevelFatal))
+	assert.Equal(t, "PANIC", LevelName(LevelPanic))
+	assert.Equal(t, "INFO+1", LevelName(slog.LevelInfo+1))
+}
+
+func TestReplaceLevelName(t *testing.T) {
+	assert.Equal(t, slog.String(slog.LevelKey, "NOTICE"), ReplaceLevelName(nil, slog.Any(slog.LevelKey, LevelNotice)))
+	assert.Equal(t, slog.String(slog.LevelKey, "WARN"), ReplaceLevelName(nil, slog.Any(slog.LevelKey, slog.LevelWarn)))
+
+	attr := slog.Any(slog.LevelKey, LevelNotice)
+	assert.Equal(t, attr, ReplaceLevelName([]string{"group"}, attr))
+	attr = slog.String("key", "value")
+	assert.Equal(t, attr, ReplaceLevelName(nil, attr))
+}
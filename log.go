@@ -2,37 +2,185 @@ package xslog
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"runtime"
-	"time"
 
+	"github.com/galecore/xslog/xdata"
 	"golang.org/x/exp/slog"
 )
 
+// exit is called by the Fatal functions after the record was handled.
+var exit = os.Exit
+
+func Trace(ctx context.Context, msg string, attrs ...slog.Attr) {
+	log(ctx, LevelTrace, 0, msg, attrs, nil)
+}
+
 func Debug(ctx context.Context, msg string, attrs ...slog.Attr) {
-	Log(ctx, slog.LevelDebug, msg, attrs)
+	log(ctx, slog.LevelDebug, 0, msg, attrs, nil)
 }
 
 func Info(ctx context.Context, msg string, attrs ...slog.Attr) {
-	Log(ctx, slog.LevelInfo, msg, attrs)
+	log(ctx, slog.LevelInfo, 0, msg, attrs, nil)
+}
+
+func Notice(ctx context.Context, msg string, attrs ...slog.Attr) {
+	log(ctx, LevelNotice, 0, msg, attrs, nil)
 }
 
 func Warn(ctx context.Context, msg string, attrs ...slog.Attr) {
-	Log(ctx, slog.LevelWarn, msg, attrs)
+	log(ctx, slog.LevelWarn, 0, msg, attrs, nil)
 }
 
 func Error(ctx context.Context, msg string, attrs ...slog.Attr) {
-	Log(ctx, slog.LevelError, msg, attrs)
+	log(ctx, slog.LevelError, 0, msg, attrs, nil)
+}
+
+// Fatal logs at LevelFatal and then exits the process with status 1.
+func Fatal(ctx context.Context, msg string, attrs ...slog.Attr) {
+	log(ctx, LevelFatal, 0, msg, attrs, nil)
+	exit(1)
+}
+
+// Panic logs at LevelPanic and then panics with msg.
+func Panic(ctx context.Context, msg string, attrs ...slog.Attr) {
+	log(ctx, LevelPanic, 0, msg, attrs, nil)
+	panic(msg)
+}
+
+// TraceKV logs at LevelTrace with attrs built from alternating keys and values, like slog.Logger.Log.
+func TraceKV(ctx context.Context, msg string, args ...any) {
+	log(ctx, LevelTrace, 0, msg, nil, args)
+}
+
+func DebugKV(ctx context.Context, msg string, args ...any) {
+	log(ctx, slog.LevelDebug, 0, msg, nil, args)
+}
+
+func InfoKV(ctx context.Context, msg string, args ...any) {
+	log(ctx, slog.LevelInfo, 0, msg, nil, args)
+}
+
+func NoticeKV(ctx context.Context, msg string, args ...any) {
+	log(ctx, LevelNotice, 0, msg, nil, args)
+}
+
+func WarnKV(ctx context.Context, msg string, args ...any) {
+	log(ctx, slog.LevelWarn, 0, msg, nil, args)
+}
+
+func ErrorKV(ctx context.Context, msg string, args ...any) {
+	log(ctx, slog.LevelError, 0, msg, nil, args)
+}
+
+func FatalKV(ctx context.Context, msg string, args ...any) {
+	log(ctx, LevelFatal, 0, msg, nil, args)
+	exit(1)
+}
+
+func PanicKV(ctx context.Context, msg string, args ...any) {
+	log(ctx, LevelPanic, 0, msg, nil, args)
+	panic(msg)
+}
+
+// Tracef logs at LevelTrace with a message formatted by fmt.Sprintf.
+// The message is only formatted if the level is enabled.
+func Tracef(ctx context.Context, format string, args ...any) {
+	logf(ctx, LevelTrace, format, args)
+}
+
+func Debugf(ctx context.Context, format string, args ...any) {
+	logf(ctx, slog.LevelDebug, format, args)
 }
 
+func Infof(ctx context.Context, format string, args ...any) {
+	logf(ctx, slog.LevelInfo, format, args)
+}
+
+func Noticef(ctx context.Context, format string, args ...any) {
+	logf(ctx, LevelNotice, format, args)
+}
+
+func Warnf(ctx context.Context, format string, args ...any) {
+	logf(ctx, slog.LevelWarn, format, args)
+}
+
+func Errorf(ctx context.Context, format string, args ...any) {
+	logf(ctx, slog.LevelError, format, args)
+}
+
+func Fatalf(ctx context.Context, format string, args ...any) {
+	logf(ctx, LevelFatal, format, args)
+	exit(1)
+}
+
+func Panicf(ctx context.Context, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	log(ctx, LevelPanic, 0, msg, nil, nil)
+	panic(msg)
+}
+
+// Log emits a record through the logger resolved by Logger, so it is safe to call with any context.
+// Attrs bound with xdata.WithAttrs are added to the record unless the logger already
+// handles them with an xdata.Handler.
 func Log(ctx context.Context, leveler slog.Leveler, msg string, attrs []slog.Attr) {
-	logger := ContextLogger(ctx)
-	if !logger.Enabled(ctx, leveler.Level()) {
+	log(ctx, leveler.Level(), 0, msg, attrs, nil)
+}
+
+// LogKV is like Log, but takes alternating keys and values like slog.Logger.Log.
+func LogKV(ctx context.Context, leveler slog.Leveler, msg string, args ...any) {
+	log(ctx, leveler.Level(), 0, msg, nil, args)
+}
+
+// Logf is like Log, but formats the message with fmt.Sprintf if the level is enabled.
+func Logf(ctx context.Context, leveler slog.Leveler, format string, args ...any) {
+	logf(ctx, leveler.Level(), format, args)
+}
+
+// LogAttrs is like Log, but lets wrapper libraries report the source of their own callers:
+// skip is the number of stack frames to ascend above the caller of LogAttrs,
+// with 0 identifying the caller of LogAttrs.
+func LogAttrs(ctx context.Context, skip int, leveler slog.Leveler, msg string, attrs ...slog.Attr) {
+	log(ctx, leveler.Level(), skip, msg, attrs, nil)
+}
+
+// With returns a new context that is bound with a child of the context logger carrying attrs.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if ctx == nil || len(attrs) == 0 {
+		return ctx
+	}
+	return WithLogger(ctx, slog.New(Logger(ctx).Handler().WithAttrs(attrs)))
+}
+
+func logf(ctx context.Context, level slog.Level, format string, args []any) {
+	logger := Logger(ctx)
+	if !logger.Enabled(ctx, level) {
 		return
 	}
+	emit(ctx, logger, level, 2, fmt.Sprintf(format, args...), nil, nil)
+}
 
+// log must be called directly by an exported function, so that skip 0 identifies its caller.
+func log(ctx context.Context, level slog.Level, skip int, msg string, attrs []slog.Attr, args []any) {
+	logger := Logger(ctx)
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	emit(ctx, logger, level, skip+2, msg, attrs, args)
+}
+
+// emit sends a record to logger, which must be enabled for level.
+// skip is the number of stack frames between emit and the frame that the record reports as its source.
+func emit(ctx context.Context, logger *slog.Logger, level slog.Level, skip int, msg string, attrs []slog.Attr, args []any) {
 	var pcs [1]uintptr
-	runtime.Callers(3, pcs[:]) // skip [Callers, log, log's caller]
-	r := slog.NewRecord(time.Now(), leveler.Level(), msg, pcs[0])
+	runtime.Callers(skip+2, pcs[:]) // skip [Callers, emit]
+	r := slog.NewRecord(xdata.Now(ctx), level, msg, pcs[0])
 	r.AddAttrs(attrs...)
-	_ = logger.Handler().Handle(ctx, r)
+	r.Add(args...)
+	handler := logger.Handler()
+	if !xdata.HandlesContextAttrs(handler) {
+		r.AddAttrs(xdata.ContextAttrs(ctx)...)
+	}
+	_ = handler.Handle(ctx, r)
 }
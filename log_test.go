@@ -0,0 +1,324 @@
+package xslog
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/galecore/xslog/util"
+	"github.com/galecore/xslog/xdata"
+	"github.com/galecore/xslog/xtee"
+	"github.com/galecore/xslog/xtesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+)
+
+func TestLog(t *testing.T) {
+	t.Run("no logger in context", func(t *testing.T) {
+		t.Cleanup(func() { SetDefault(nil) })
+		l := util.NewBufferedLogger()
+		SetDefault(slog.New(xtesting.NewHandler(l)))
+
+		assert.NotPanics(t, func() {
+			Info(context.Background(), "test", slog.Int("int", 1))
+			Info(nil, "test")
+		})
+		assert.Equal(t, "INFO: test [int=1]INFO: test []", l.B.String())
+	})
+
+	t.Run("merges context attrs", func(t *testing.T) {
+		l := util.NewBufferedLogger()
+		ctx := WithLogger(context.Background(), slog.New(xtesting.NewHandler(l)))
+		ctx = xdata.WithAttrs(ctx, slog.String("key", "value"))
+
+		Warn(ctx, "test", slog.Int("int", 1))
+		assert.Equal(t, "WARN: test [int=1 key=value]", l.B.String())
+	})
+
+	t.Run("does not duplicate context attrs", func(t *testing.T) {
+		l := util.NewBufferedLogger()
+		ctx := WithLogger(context.Background(), slog.New(xdata.NewHandler(xtesting.NewHandler(l))))
+		ctx = xdata.WithAttrs(ctx, slog.String("key", "value"))
+
+		Error(ctx, "test", slog.Int("int", 1))
+		assert.Equal(t, "ERROR: test [int=1 key=value]", l.B.String())
+	})
+
+	t.Run("does not duplicate context attrs of wrapped handlers", func(t *testing.T) {
+		l := util.NewBufferedLogger()
+		handler := xtee.NewHandler(xdata.NewHandler(xtesting.NewHandler(l)))
+		ctx := WithLogger(context.Background(), slog.New(handler))
+		ctx = xdata.WithAttrs(ctx, slog.String("tenant", "acme"))
+
+		var registry Registry
+		Info(ctx, "test")
+		Info(WithLogger(ctx, registry.Named(ctx, "billing")), "named")
+		assert.Equal(t, "INFO: test [tenant=acme]INFO: named [logger=billing tenant=acme]", l.B.String())
+	})
+
+	t.Run("adds context attrs to tee branches without xdata", func(t *testing.T) {
+		withData, plain := util.NewBufferedLogger(), util.NewBufferedLogger()
+		handler := xtee.NewHandler(xdata.NewHandler(xtesting.NewHandler(withData)), xtesting.NewHandler(plain))
+		ctx := WithLogger(context.Background(), slog.New(handler))
+		ctx = xdata.WithAttrs(ctx, slog.String("tenant", "acme"))
+
+		Info(ctx, "hi", slog.Int("n", 1))
+		assert.Equal(t, "INFO: hi [n=1 tenant=acme]", withData.B.String())
+		assert.Equal(t, "INFO: hi [n=1 tenant=acme]", plain.B.String())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		l := util.NewBufferedLogger()
+		handler := slog.NewTextHandler(l.B, &slog.HandlerOptions{Level: slog.LevelWarn})
+		ctx := WithLogger(context.Background(), slog.New(handler))
+
+		Debug(ctx, "test")
+		Info(ctx, "test")
+		assert.Empty(t, l.B.String())
+	})
+}
+
+type recordHandler struct {
+	records *[]slog.Record
+	attrs   []slog.Attr
+}
+
+func newRecordHandler() *recordHandler {
+	return &recordHandler{records: new([]slog.Record)}
+}
+
+func (h *recordHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= LevelTrace
+}
+
+func (h *recordHandler) Handle(_ context.Context, record slog.Record) error {
+	record.AddAttrs(h.attrs...)
+	*h.records = append(*h.records, record)
+	return nil
+}
+
+func (h *recordHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &recordHandler{records: h.records, attrs: append(append([]slog.Attr(nil), h.attrs...), attrs...)}
+}
+
+func (h *recordHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+func recordSource(record slog.Record) (string, int) {
+	frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+	return filepath.Base(frame.File), frame.Line
+}
+
+func TestLog_Source(t *testing.T) {
+	t.Cleanup(func() { exit = os.Exit })
+	exit = func(int) {}
+
+	tests := []struct {
+		name string
+		log  func(ctx context.Context) int
+	}{
+		{"Trace", func(ctx context.Context) int {
+			_, _, line, _ := runtime.Caller(0)
+			Trace(ctx, "msg")
+			return line + 1
+		}},
+		{"Debug", func(ctx context.Context) int {
+			_, _, line, _ := runtime.Caller(0)
+			Debug(ctx, "msg")
+			return line + 1
+		}},
+		{"Info", func(ctx context.Context) int {
+			_, _, line, _ := runtime.Caller(0)
+			Info(ctx, "msg")
+			return line + 1
+		}},
+		{"Notice", func(ctx context.Context) int {
+			_, _, line, _ := runtime.Caller(0)
+			Notice(ctx, "msg")
+			return line + 1
+		}},
+		{"Warn", func(ctx context.Context) int {
+			_, _, line, _ := runtime.Caller(0)
+			Warn(ctx, "msg")
+			return line + 1
+		}},
+		{"Error", func(ctx context.Context) int {
+			_, _, line, _ := runtime.Caller(0)
+			Error(ctx, "msg")
+			return line + 1
+		}},
+		{"Fatal", func(ctx context.Context) int {
+			_, _, line, _ := runtime.Caller(0)
+			Fatal(ctx, "msg")
+			return line + 1
+		}},
+		{"Panic", func(ctx context.Context) (line int) {
+			defer func() { _ = recover() }()
+			_, _, line, _ = runtime.Caller(0)
+			line += 2
+			Panic(ctx, "msg")
+			return line
+		}},
+		{"InfoKV", func(ctx context.Context) int {
+			_, _, line, _ := runtime.Caller(0)
+			InfoKV(ctx, "msg", "key", "value")
+			return line + 1
+		}},
+		{"FatalKV", func(ctx context.Context) int {
+			_, _, line, _ := runtime.Caller(0)
+			FatalKV(ctx, "msg")
+			return line + 1
+		}},
+		{"Infof", func(ctx context.Context) int {
+			_, _, line, _ := runtime.Caller(0)
+			Infof(ctx, "msg %d", 1)
+			return line + 1
+		}},
+		{"Fatalf", func(ctx context.Context) int {
+			_, _, line, _ := runtime.Caller(0)
+			Fatalf(ctx, "msg %d", 1)
+			return line + 1
+		}},
+		{"Log", func(ctx context.Context) int {
+			_, _, line, _ := runtime.Caller(0)
+			Log(ctx, slog.LevelInfo, "msg", nil)
+			return line + 1
+		}},
+		{"LogKV", func(ctx context.Context) int {
+			_, _, line, _ := runtime.Caller(0)
+			LogKV(ctx, slog.LevelInfo, "msg")
+			return line + 1
+		}},
+		{"Logf", func(ctx context.Context) int {
+			_, _, line, _ := runtime.Caller(0)
+			Logf(ctx, slog.LevelInfo, "msg")
+			return line + 1
+		}},
+		{"LogAttrs", func(ctx context.Context) int {
+			_, _, line, _ := runtime.Caller(0)
+			LogAttrs(ctx, 0, slog.LevelInfo, "msg")
+			return line + 1
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newRecordHandler()
+			line := tt.log(WithLogger(context.Background(), slog.New(h)))
+			require.Len(t, *h.records, 1)
+			file, gotLine := recordSource((*h.records)[0])
+			assert.Equal(t, "log_test.go", file)
+			assert.Equal(t, line, gotLine)
+		})
+	}
+}
+
+func TestLogAttrs(t *testing.T) {
+	h := newRecordHandler()
+	ctx := WithLogger(context.Background(), slog.New(h))
+
+	wrapper := func(ctx context.Context, msg string) {
+		LogAttrs(ctx, 1, slog.LevelInfo, msg, slog.Int("int", 1))
+	}
+	_, _, line, _ := runtime.Caller(0)
+	wrapper(ctx, "msg")
+
+	require.Len(t, *h.records, 1)
+	file, gotLine := recordSource((*h.records)[0])
+	assert.Equal(t, "log_test.go", file)
+	assert.Equal(t, line+1, gotLine)
+}
+
+type countingStringer struct {
+	calls *int
+}
+
+func (s countingStringer) String() string {
+	*s.calls++
+	return "value"
+}
+
+func TestLogf(t *testing.T) {
+	l := util.NewBufferedLogger()
+	handler := slog.NewTextHandler(l.B, &slog.HandlerOptions{Level: slog.LevelInfo})
+	ctx := WithLogger(context.Background(), slog.New(handler))
+
+	var calls int
+	Debugf(ctx, "debug %s", countingStringer{&calls})
+	assert.Equal(t, 0, calls)
+	assert.Empty(t, l.B.String())
+
+	Infof(ctx, "info %s", countingStringer{&calls})
+	assert.Equal(t, 1, calls)
+	assert.Contains(t, l.B.String(), `msg="info value"`)
+}
+
+func TestLogKV(t *testing.T) {
+	l := util.NewBufferedLogger()
+	ctx := WithLogger(context.Background(), slog.New(xtesting.NewHandler(l)))
+
+	InfoKV(ctx, "test", "key", "value", slog.Int("int", 1), "dangling")
+	assert.Equal(t, "INFO: test [key=value int=1 !BADKEY=dangling]", l.B.String())
+}
+
+func TestFatal(t *testing.T) {
+	t.Cleanup(func() { exit = os.Exit })
+	var code int
+	exit = func(c int) { code = c }
+
+	l := util.NewBufferedLogger()
+	ctx := WithLogger(context.Background(), slog.New(xtesting.NewHandler(l)))
+	Fatal(ctx, "test", slog.Int("int", 1))
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "ERROR+4: test [int=1]", l.B.String())
+}
+
+func TestPanic(t *testing.T) {
+	l := util.NewBufferedLogger()
+	ctx := WithLogger(context.Background(), slog.New(xtesting.NewHandler(l)))
+
+	assert.PanicsWithValue(t, "test", func() {
+		Panic(ctx, "test", slog.Int("int", 1))
+	})
+	assert.PanicsWithValue(t, "test 2", func() {
+		Panicf(ctx, "test %d", 2)
+	})
+	assert.Equal(t, "ERROR+8: test [int=1]ERROR+8: test 2 []", l.B.String())
+}
+
+func TestWith(t *testing.T) {
+	l := util.NewBufferedLogger()
+	parent := WithLogger(context.Background(), slog.New(xtesting.NewHandler(l)))
+	ctx := With(parent, slog.String("key", "value"))
+
+	Info(ctx, "child")
+	Info(parent, "parent")
+	assert.Equal(t, "INFO: child [key=value]INFO: parent []", l.B.String())
+	assert.Equal(t, parent, With(parent))
+}
+
+func TestLog_Deterministic(t *testing.T) {
+	run := func() string {
+		var buf bytes.Buffer
+		ctx := WithLogger(context.Background(), slog.New(slog.NewJSONHandler(&buf, nil)))
+		ctx = xdata.WithClock(ctx, xtesting.NewFakeClock(time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC), time.Millisecond))
+		ctx = xdata.WithIDSource(ctx, xtesting.NewSequentialIDs("req"))
+		ctx = xdata.EnsureRequestID(ctx)
+		ctx = StartEvent(ctx)
+
+		Info(ctx, "started")
+		EmitEvent(ctx, slog.LevelInfo, "finished")
+		return buf.String()
+	}
+
+	first := run()
+	assert.Equal(t, first, run())
+	assert.Equal(t, `{"time":"2023-07-01T12:00:00.001Z","level":"INFO","msg":"started","request_id":"req-1"}
+{"time":"2023-07-01T12:00:00.003Z","level":"INFO","msg":"finished","duration":2000000,"request_id":"req-1"}
+`, first)
+}
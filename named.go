@@ -0,0 +1,216 @@
+package xslog
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/galecore/xslog/xdata"
+	"golang.org/x/exp/slog"
+)
+
+// LoggerKey is the attr key under which named loggers record their name.
+const LoggerKey = "logger"
+
+// NameSeparator separates the segments of logger names, e.g. "billing.invoices.pdf".
+const NameSeparator = "."
+
+// NamedLevel describes the effective level of a named logger.
+type NamedLevel struct {
+	Name string
+	// Level is the configured level of the logger. It is only meaningful if Configured is set.
+	// Records below it are dropped; the underlying handler may drop records above it as well.
+	Level slog.Level
+	// From is the name of the nearest ancestor, or the logger itself, that configures Level.
+	From string
+	// Configured reports whether the logger or one of its ancestors has a level.
+	// Unconfigured loggers defer to the level of the underlying handler.
+	Configured bool
+}
+
+// Registry holds the configuration of a hierarchy of named loggers. A logger is configured by the
+// nearest of its ancestors that has a level or attrs, where "billing" and "" are ancestors of "billing.invoices".
+// The zero value is ready to use.
+type Registry struct {
+	mu      sync.RWMutex
+	levels  map[string]slog.Level
+	attrs   map[string][]slog.Attr
+	loggers map[string]*namedEntry
+}
+
+type namedEntry struct {
+	level atomic.Pointer[NamedLevel]
+}
+
+var defaultRegistry Registry
+
+// Named returns the logger of ctx named name from the package-level registry. See Registry.Named.
+func Named(ctx context.Context, name string) *slog.Logger {
+	return defaultRegistry.Named(ctx, name)
+}
+
+// SetLevel sets the level of the logger named prefix and its descendants in the package-level registry.
+func SetLevel(prefix string, level slog.Leveler) {
+	defaultRegistry.SetLevel(prefix, level)
+}
+
+// UnsetLevel removes the level of the logger named prefix from the package-level registry.
+func UnsetLevel(prefix string) {
+	defaultRegistry.UnsetLevel(prefix)
+}
+
+// SetNamedAttrs sets the attrs of the logger named prefix and its descendants in the package-level registry.
+func SetNamedAttrs(prefix string, attrs ...slog.Attr) {
+	defaultRegistry.SetAttrs(prefix, attrs...)
+}
+
+// NamedLevels lists the loggers of the package-level registry with their effective levels.
+func NamedLevels() []NamedLevel {
+	return defaultRegistry.Levels()
+}
+
+// Named returns a logger derived from the logger of ctx that is tagged with LoggerKey=name and
+// carries the attrs of its nearest configured ancestor. The level of the returned logger follows
+// the configuration of the registry, including changes made after Named returned.
+func (r *Registry) Named(ctx context.Context, name string) *slog.Logger {
+	entry, attrs := r.entry(name)
+	handler := Logger(ctx).Handler().WithAttrs(append([]slog.Attr{slog.String(LoggerKey, name)}, attrs...))
+	return slog.New(&namedHandler{Handler: handler, entry: entry})
+}
+
+// SetLevel sets the level of the logger named prefix and of all its descendants that have no closer configuration.
+// A level filters records in addition to the underlying handler, so it can raise the threshold of the handler
+// but not lower it: records the handler drops stay dropped.
+func (r *Registry) SetLevel(prefix string, level slog.Leveler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.levels == nil {
+		r.levels = make(map[string]slog.Level)
+	}
+	r.levels[prefix] = level.Level()
+	r.updateLocked()
+}
+
+// UnsetLevel removes the level of the logger named prefix, so that it inherits the level of its ancestors again.
+func (r *Registry) UnsetLevel(prefix string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.levels, prefix)
+	r.updateLocked()
+}
+
+// SetAttrs sets the attrs of the logger named prefix and of all its descendants that have no closer configuration.
+// Passing no attrs removes the configuration. Attrs apply to loggers returned by later calls to Named.
+func (r *Registry) SetAttrs(prefix string, attrs ...slog.Attr) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(attrs) == 0 {
+		delete(r.attrs, prefix)
+		return
+	}
+	if r.attrs == nil {
+		r.attrs = make(map[string][]slog.Attr)
+	}
+	r.attrs[prefix] = attrs
+}
+
+// Levels lists all loggers returned by Named so far with their effective levels, ordered by name.
+func (r *Registry) Levels() []NamedLevel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	levels := make([]NamedLevel, 0, len(r.loggers))
+	for _, entry := range r.loggers {
+		levels = append(levels, *entry.level.Load())
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		return levels[i].Name < levels[j].Name
+	})
+	return levels
+}
+
+func (r *Registry) entry(name string) (*namedEntry, []slog.Attr) {
+	r.mu.RLock()
+	entry, ok := r.loggers[name]
+	attrs := r.attrsLocked(name)
+	r.mu.RUnlock()
+	if ok {
+		return entry, attrs
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry, ok = r.loggers[name]; !ok {
+		entry = new(namedEntry)
+		entry.level.Store(r.levelLocked(name))
+		if r.loggers == nil {
+			r.loggers = make(map[string]*namedEntry)
+		}
+		r.loggers[name] = entry
+	}
+	return entry, r.attrsLocked(name)
+}
+
+func (r *Registry) updateLocked() {
+	for name, entry := range r.loggers {
+		entry.level.Store(r.levelLocked(name))
+	}
+}
+
+func (r *Registry) levelLocked(name string) *NamedLevel {
+	for prefix, ok := name, true; ok; prefix, ok = parentName(prefix) {
+		if level, configured := r.levels[prefix]; configured {
+			return &NamedLevel{Name: name, Level: level, From: prefix, Configured: true}
+		}
+	}
+	return &NamedLevel{Name: name}
+}
+
+func (r *Registry) attrsLocked(name string) []slog.Attr {
+	for prefix, ok := name, true; ok; prefix, ok = parentName(prefix) {
+		if attrs, configured := r.attrs[prefix]; configured {
+			return attrs
+		}
+	}
+	return nil
+}
+
+// parentName returns the name of the parent of the logger named name.
+// The root logger "" has no parent.
+func parentName(name string) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+	if i := strings.LastIndex(name, NameSeparator); i >= 0 {
+		return name[:i], true
+	}
+	return "", true
+}
+
+// namedHandler filters records below the level configured for its logger before the level check of the
+// wrapped handler. It cannot enable levels the wrapped handler drops, since handlers such as xzerolog and xtee
+// check their own levels again in Handle.
+type namedHandler struct {
+	slog.Handler
+	entry *namedEntry
+}
+
+func (h *namedHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if named := h.entry.level.Load(); named.Configured && level < named.Level {
+		return false
+	}
+	return h.Handler.Enabled(ctx, level)
+}
+
+func (h *namedHandler) HandlesContextAttrs() bool {
+	return xdata.HandlesContextAttrs(h.Handler)
+}
+
+func (h *namedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &namedHandler{Handler: h.Handler.WithAttrs(attrs), entry: h.entry}
+}
+
+func (h *namedHandler) WithGroup(name string) slog.Handler {
+	return &namedHandler{Handler: h.Handler.WithGroup(name), entry: h.entry}
+}
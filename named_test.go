@@ -0,0 +1,146 @@
+package xslog
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/galecore/xslog/util"
+	"github.com/galecore/xslog/xdata"
+	"github.com/galecore/xslog/xtesting"
+	"github.com/galecore/xslog/xzerolog"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+)
+
+func TestRegistry_Named(t *testing.T) {
+	t.Run("tags logger name", func(t *testing.T) {
+		var r Registry
+		l := util.NewBufferedLogger()
+		ctx := WithLogger(context.Background(), slog.New(xtesting.NewHandler(l)))
+
+		r.Named(ctx, "billing.invoices").Info("test", slog.Int("int", 1))
+		assert.Equal(t, "INFO: test [logger=billing.invoices int=1]", l.B.String())
+	})
+
+	t.Run("defers to handler level when unconfigured", func(t *testing.T) {
+		var r Registry
+		l := util.NewBufferedLogger()
+		handler := slog.NewTextHandler(l.B, &slog.HandlerOptions{Level: slog.LevelWarn})
+		ctx := WithLogger(context.Background(), slog.New(handler))
+
+		logger := r.Named(ctx, "billing")
+		assert.False(t, logger.Enabled(ctx, slog.LevelInfo))
+		assert.True(t, logger.Enabled(ctx, slog.LevelWarn))
+	})
+
+	t.Run("inherits level from nearest ancestor", func(t *testing.T) {
+		var r Registry
+		ctx := WithLogger(context.Background(), slog.New(xtesting.NewHandler(util.NewBufferedLogger())))
+		r.SetLevel("", slog.LevelError)
+		r.SetLevel("billing", slog.LevelDebug)
+
+		pdf := r.Named(ctx, "billing.invoices.pdf")
+		billingx := r.Named(ctx, "billingx")
+		assert.True(t, pdf.Enabled(ctx, slog.LevelDebug))
+		assert.False(t, billingx.Enabled(ctx, slog.LevelWarn))
+
+		r.SetLevel("billing.invoices", slog.LevelWarn)
+		assert.False(t, pdf.Enabled(ctx, slog.LevelInfo))
+		assert.True(t, pdf.Enabled(ctx, slog.LevelWarn))
+
+		r.UnsetLevel("billing.invoices")
+		assert.True(t, pdf.Enabled(ctx, slog.LevelDebug))
+	})
+
+	t.Run("cannot lower the level of the handler", func(t *testing.T) {
+		var r Registry
+		var buf bytes.Buffer
+		base := zerolog.New(&buf).Level(zerolog.InfoLevel)
+		ctx := WithLogger(context.Background(), slog.New(xzerolog.NewHandler(&base)))
+		r.SetLevel("billing", slog.LevelDebug)
+		r.SetLevel("shipping", slog.LevelWarn)
+
+		billing := r.Named(ctx, "billing")
+		assert.False(t, billing.Enabled(ctx, slog.LevelDebug))
+		assert.True(t, billing.Enabled(ctx, slog.LevelInfo))
+		shipping := r.Named(ctx, "shipping")
+		assert.False(t, shipping.Enabled(ctx, slog.LevelInfo))
+
+		billing.Debug("dropped")
+		billing.Info("written")
+		shipping.Info("dropped")
+		assert.Contains(t, buf.String(), `{"level":"info","logger":"billing",`)
+		assert.Contains(t, buf.String(), `"message":"written"}`)
+		assert.NotContains(t, buf.String(), "dropped")
+	})
+
+	t.Run("inherits attrs from nearest ancestor", func(t *testing.T) {
+		var r Registry
+		l := util.NewBufferedLogger()
+		ctx := WithLogger(context.Background(), slog.New(xtesting.NewHandler(l)))
+		r.SetAttrs("billing", slog.String("team", "payments"))
+
+		r.Named(ctx, "billing.invoices").Info("test")
+		r.Named(ctx, "shipping").Info("test")
+		r.SetAttrs("billing")
+		r.Named(ctx, "billing.invoices").Info("test")
+		assert.Equal(t, "INFO: test [logger=billing.invoices team=payments]INFO: test [logger=shipping]INFO: test [logger=billing.invoices]", l.B.String())
+	})
+
+	t.Run("does not duplicate context attrs", func(t *testing.T) {
+		var r Registry
+		l := util.NewBufferedLogger()
+		ctx := WithLogger(context.Background(), slog.New(xdata.NewHandler(xtesting.NewHandler(l))))
+		ctx = xdata.WithAttrs(ctx, slog.String("key", "value"))
+
+		Info(WithLogger(ctx, r.Named(ctx, "billing")), "test")
+		assert.Equal(t, "INFO: test [logger=billing key=value]", l.B.String())
+	})
+}
+
+func TestRegistry_Levels(t *testing.T) {
+	var r Registry
+	ctx := context.Background()
+	r.SetLevel("billing", slog.LevelDebug)
+	r.Named(ctx, "shipping")
+	r.Named(ctx, "billing.invoices")
+	r.Named(ctx, "billing")
+
+	assert.Equal(t, []NamedLevel{
+		{Name: "billing", Level: slog.LevelDebug, From: "billing", Configured: true},
+		{Name: "billing.invoices", Level: slog.LevelDebug, From: "billing", Configured: true},
+		{Name: "shipping"},
+	}, r.Levels())
+}
+
+func TestNamed(t *testing.T) {
+	t.Cleanup(func() { defaultRegistry = Registry{} })
+	l := util.NewBufferedLogger()
+	ctx := WithLogger(context.Background(), slog.New(xtesting.NewHandler(l)))
+
+	SetLevel("payments", slog.LevelWarn)
+	SetNamedAttrs("payments", slog.String("team", "payments"))
+	logger := Named(ctx, "payments.refunds")
+	logger.Info("dropped")
+	logger.Warn("test")
+	UnsetLevel("payments")
+	logger.Info("test")
+
+	assert.Equal(t, "WARN: test [logger=payments.refunds team=payments]INFO: test [logger=payments.refunds team=payments]", l.B.String())
+	assert.Equal(t, []NamedLevel{{Name: "payments.refunds"}}, NamedLevels())
+}
+
+func TestParentName(t *testing.T) {
+	parent, ok := parentName("a.b.c")
+	assert.Equal(t, "a.b", parent)
+	assert.True(t, ok)
+
+	parent, ok = parentName("a")
+	assert.Equal(t, "", parent)
+	assert.True(t, ok)
+
+	_, ok = parentName("")
+	assert.False(t, ok)
+}
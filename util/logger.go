@@ -3,6 +3,7 @@ package util
 import (
 	"bytes"
 	"fmt"
+	"sync"
 )
 
 type BufferedLogger struct {
@@ -27,3 +28,31 @@ func (h BufferedLogger) Log(args ...any) {
 func (h BufferedLogger) Logf(format string, args ...any) {
 	h.Log(append([]any{format}, args...)...)
 }
+
+// SyncBufferedLogger is a BufferedLogger that is safe for concurrent use,
+// e.g. by tests whose records are logged from other goroutines.
+type SyncBufferedLogger struct {
+	mu sync.Mutex
+	l  BufferedLogger
+}
+
+func NewSyncBufferedLogger() *SyncBufferedLogger {
+	return &SyncBufferedLogger{l: *NewBufferedLogger()}
+}
+
+func (h *SyncBufferedLogger) Log(args ...any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.l.Log(args...)
+}
+
+func (h *SyncBufferedLogger) Logf(format string, args ...any) {
+	h.Log(append([]any{format}, args...)...)
+}
+
+// String returns everything logged so far.
+func (h *SyncBufferedLogger) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.l.B.String()
+}
@@ -2,6 +2,8 @@ package util
 
 import (
 	"bytes"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -63,3 +65,17 @@ func TestBufferedLogger_Logf(t *testing.T) {
 		assert.Equal(t, "hello world", h.B.String())
 	})
 }
+
+func TestSyncBufferedLogger(t *testing.T) {
+	h := NewSyncBufferedLogger()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.Logf("hello %s;", "world")
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, strings.Repeat("hello world;", 10), h.String())
+}
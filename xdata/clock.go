@@ -0,0 +1,97 @@
+package xdata
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+)
+
+// Clock tells the time of records and the durations logged by the packages of this module.
+type Clock interface {
+	Now() time.Time
+}
+
+// IDSource generates request IDs.
+type IDSource interface {
+	NewID() string
+}
+
+// SystemClock is the Clock used when no clock is bound with a context. It returns time.Now.
+var SystemClock Clock = systemClock{}
+
+// RandomIDs is the IDSource used when no source is bound with a context. It returns random version 4 UUIDs.
+var RandomIDs IDSource = randomIDs{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+type randomIDs struct{}
+
+func (randomIDs) NewID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("xdata: read random request ID: %v", err))
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// WithClock returns a new context that is bound with clock. Nil clocks are ignored.
+func WithClock(ctx context.Context, clock Clock) context.Context {
+	if ctx == nil || clock == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxClockKey, clock)
+}
+
+// ContextClock returns the Clock bound with ctx. If no clock is bound, it returns SystemClock.
+func ContextClock(ctx context.Context) Clock {
+	if clock, ok := BoundClock(ctx); ok {
+		return clock
+	}
+	return SystemClock
+}
+
+// BoundClock returns the Clock bound with ctx and reports whether there is one, for handlers that
+// replace record times only when a clock is bound.
+func BoundClock(ctx context.Context) (Clock, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	clock, ok := ctx.Value(ctxClockKey).(Clock)
+	return clock, ok
+}
+
+// Now returns the current time of the Clock bound with ctx.
+func Now(ctx context.Context) time.Time {
+	return ContextClock(ctx).Now()
+}
+
+// Since returns the time elapsed since start according to the Clock bound with ctx.
+func Since(ctx context.Context, start time.Time) time.Duration {
+	return Now(ctx).Sub(start)
+}
+
+// WithIDSource returns a new context that is bound with source. Nil sources are ignored.
+func WithIDSource(ctx context.Context, source IDSource) context.Context {
+	if ctx == nil || source == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxIDSourceKey, source)
+}
+
+// ContextIDSource returns the IDSource bound with ctx. If no source is bound, it returns RandomIDs.
+func ContextIDSource(ctx context.Context) IDSource {
+	if ctx == nil {
+		return RandomIDs
+	}
+	if source, ok := ctx.Value(ctxIDSourceKey).(IDSource); ok {
+		return source
+	}
+	return RandomIDs
+}
@@ -0,0 +1,34 @@
+package xdata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/galecore/xslog/xtesting"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithClock(t *testing.T) {
+	assert.Equal(t, SystemClock, ContextClock(nil))
+	assert.Equal(t, SystemClock, ContextClock(context.Background()))
+	assert.Equal(t, RandomIDs, ContextIDSource(context.Background()))
+
+	start := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	clock := xtesting.NewFakeClock(start, time.Second)
+	ctx := WithClock(context.Background(), clock)
+	assert.Equal(t, start, Now(ctx))
+	assert.Equal(t, time.Second, Since(ctx, start))
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, start.Add(time.Minute+2*time.Second), Now(ctx))
+}
+
+func TestEvent_Clock(t *testing.T) {
+	ctx := WithClock(context.Background(), xtesting.NewFakeClock(time.Unix(0, 0), time.Millisecond))
+	_, e := WithEvent(ctx)
+	stop := e.Time("db")
+	stop()
+	assert.Equal(t, time.Millisecond, e.Attrs()[0].Value.Duration())
+	assert.Equal(t, 3*time.Millisecond, e.Elapsed())
+}
@@ -2,8 +2,8 @@ package xdata
 
 import (
 	"context"
+	"sync/atomic"
 
-	"github.com/galecore/xslog/util"
 	"golang.org/x/exp/slog"
 )
 
@@ -11,26 +11,96 @@ type ctxKey int
 
 const (
 	ctxFieldsKey ctxKey = iota
+	ctxRequestIDKey
+	ctxEventKey
+	ctxClockKey
+	ctxIDSourceKey
 )
 
+// frame is a link of the persistent chain of attrs bound with a context.
+// Each WithAttrs call adds one frame on top of the frame of its parent context,
+// so binding attrs never copies the attrs bound before.
+type frame struct {
+	attrs  []slog.Attr
+	parent *frame
+	n      int // number of attrs in the chain ending with this frame
+
+	flat atomic.Pointer[[]slog.Attr] // cached result of flatten
+}
+
+func newFrame(parent *frame, attrs []slog.Attr) *frame {
+	f := &frame{attrs: attrs, parent: parent, n: len(attrs)}
+	if parent != nil {
+		f.n += parent.n
+	}
+	return f
+}
+
+// flatten returns all attrs of the chain ending with f, in the order they were bound.
+// The result is computed once per frame, reusing the cached result of the nearest flattened ancestor.
+func (f *frame) flatten() []slog.Attr {
+	if f == nil {
+		return nil
+	}
+	if flat := f.flat.Load(); flat != nil {
+		return *flat
+	}
+	if f.parent == nil {
+		return f.attrs
+	}
+
+	flat := make([]slog.Attr, f.n)
+	i := f.n
+	for cur := f; cur != nil; cur = cur.parent {
+		if cached := cur.flat.Load(); cached != nil {
+			copy(flat, *cached)
+			break
+		}
+		i -= len(cur.attrs)
+		copy(flat[i:], cur.attrs)
+	}
+	f.flat.Store(&flat)
+	return flat
+}
+
+func contextFrame(ctx context.Context) *frame {
+	if ctx == nil {
+		return nil
+	}
+	f, _ := ctx.Value(ctxFieldsKey).(*frame)
+	return f
+}
+
 // WithAttrs returns a new context that is bound with given slog attrs and based on parent ctx.
 func WithAttrs(ctx context.Context, fields ...slog.Attr) context.Context {
 	if len(fields) == 0 || ctx == nil {
 		return ctx
 	}
-	return context.WithValue(ctx, ctxFieldsKey, util.Merge(ContextAttrs(ctx), fields))
+	return context.WithValue(ctx, ctxFieldsKey, newFrame(contextFrame(ctx), fields))
+}
+
+// WithGroup returns a new context that is bound with a group named name holding attrs and based on parent ctx.
+func WithGroup(ctx context.Context, name string, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+	return WithAttrs(ctx, slog.Attr{Key: name, Value: slog.GroupValue(attrs...)})
 }
 
 // ContextAttrs returns slog attrs bound with ctx. If no attrs are bound, it returns nil.
+// The returned slice is shared between callers and must not be modified.
 func ContextAttrs(ctx context.Context) []slog.Attr {
-	if ctx == nil {
-		return nil
-	}
-	fs, _ := ctx.Value(ctxFieldsKey).([]slog.Attr)
-	return fs
+	return contextFrame(ctx).flatten()
 }
 
 // TransferAttrs returns a new context that is bound with slog attrs from src and based on dst.
 func TransferAttrs(dst context.Context, src context.Context) context.Context {
-	return WithAttrs(dst, ContextAttrs(src)...)
+	f := contextFrame(src)
+	if f == nil || dst == nil {
+		return dst
+	}
+	if contextFrame(dst) == nil {
+		return context.WithValue(dst, ctxFieldsKey, f)
+	}
+	return WithAttrs(dst, f.flatten()...)
 }
@@ -2,6 +2,7 @@ package xdata
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -29,3 +30,55 @@ func TestContextAttrs(t *testing.T) {
 	assert.Equal(t, []slog.Attr{slog.String("key", "value")}, ContextAttrs(ctx))
 
 }
+
+func TestWithAttrs_Chain(t *testing.T) {
+	root := WithAttrs(context.Background(), slog.String("a", "1"))
+	left := WithAttrs(root, slog.String("b", "2"), slog.String("c", "3"))
+	right := WithAttrs(root, slog.String("d", "4"))
+	leaf := WithAttrs(left, slog.String("e", "5"))
+
+	assert.Equal(t, []slog.Attr{slog.String("a", "1")}, ContextAttrs(root))
+	assert.Equal(t, []slog.Attr{slog.String("a", "1"), slog.String("b", "2"), slog.String("c", "3")}, ContextAttrs(left))
+	assert.Equal(t, []slog.Attr{slog.String("a", "1"), slog.String("d", "4")}, ContextAttrs(right))
+	assert.Equal(t, []slog.Attr{slog.String("a", "1"), slog.String("b", "2"), slog.String("c", "3"), slog.String("e", "5")}, ContextAttrs(leaf))
+	assert.Equal(t, ContextAttrs(leaf), ContextAttrs(leaf))
+	assert.Equal(t, root, WithAttrs(root))
+}
+
+func TestTransferAttrs_Merge(t *testing.T) {
+	src := WithAttrs(context.Background(), slog.String("src", "value"))
+	dst := WithAttrs(context.Background(), slog.String("dst", "value"))
+
+	assert.Equal(t, []slog.Attr{slog.String("dst", "value"), slog.String("src", "value")}, ContextAttrs(TransferAttrs(dst, src)))
+	assert.Equal(t, dst, TransferAttrs(dst, context.Background()))
+}
+
+func BenchmarkWithAttrs(b *testing.B) {
+	for _, depth := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ctx := context.Background()
+				for j := 0; j < depth; j++ {
+					ctx = WithAttrs(ctx, slog.Int("depth", j))
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkContextAttrs(b *testing.B) {
+	for _, depth := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			ctx := context.Background()
+			for j := 0; j < depth; j++ {
+				ctx = WithAttrs(ctx, slog.Int("depth", j))
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = ContextAttrs(ctx)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package xdata
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// Event accumulates the attrs of a canonical log line: one wide record emitted at the end of a unit of work,
+// such as a request, that carries everything recorded while it ran. It is safe for concurrent use,
+// so goroutines fanned out from the context of the event can add to it. A nil *Event ignores all calls.
+type Event struct {
+	clock Clock
+	start time.Time
+
+	mu    sync.Mutex
+	attrs []slog.Attr
+	index map[string]int
+}
+
+// WithEvent returns a new context that is bound with a new Event and based on parent ctx.
+// The event measures time with the Clock bound with ctx.
+func WithEvent(ctx context.Context) (context.Context, *Event) {
+	clock := ContextClock(ctx)
+	e := &Event{clock: clock, start: clock.Now(), index: make(map[string]int)}
+	if ctx == nil {
+		return ctx, e
+	}
+	return context.WithValue(ctx, ctxEventKey, e), e
+}
+
+// ContextEvent returns the Event bound with ctx. If no event is bound, it returns nil.
+func ContextEvent(ctx context.Context) *Event {
+	if ctx == nil {
+		return nil
+	}
+	e, _ := ctx.Value(ctxEventKey).(*Event)
+	return e
+}
+
+// Add sets attrs on e. An attr replaces the previous attr with the same key, keeping its position.
+func (e *Event) Add(attrs ...slog.Attr) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, attr := range attrs {
+		e.setLocked(attr)
+	}
+}
+
+// Count adds delta to the counter named key.
+func (e *Event) Count(key string, delta int64) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if i, ok := e.index[key]; ok && e.attrs[i].Value.Kind() == slog.KindInt64 {
+		e.attrs[i].Value = slog.Int64Value(e.attrs[i].Value.Int64() + delta)
+		return
+	}
+	e.setLocked(slog.Int64(key, delta))
+}
+
+// AddDuration adds d to the timer named key.
+func (e *Event) AddDuration(key string, d time.Duration) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if i, ok := e.index[key]; ok && e.attrs[i].Value.Kind() == slog.KindDuration {
+		e.attrs[i].Value = slog.DurationValue(e.attrs[i].Value.Duration() + d)
+		return
+	}
+	e.setLocked(slog.Duration(key, d))
+}
+
+// Time starts measuring an operation and returns a function that adds its duration to the timer named key.
+// Repeated operations accumulate, e.g. `defer e.Time("db")()` around every query.
+func (e *Event) Time(key string) (stop func()) {
+	start := e.now()
+	return func() {
+		e.AddDuration(key, e.now().Sub(start))
+	}
+}
+
+// Attrs returns a snapshot of the attrs of e, in the order their keys were first set.
+func (e *Event) Attrs() []slog.Attr {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]slog.Attr(nil), e.attrs...)
+}
+
+// Elapsed returns the time passed since the event was created.
+func (e *Event) Elapsed() time.Duration {
+	if e == nil {
+		return 0
+	}
+	return e.now().Sub(e.start)
+}
+
+// now returns the current time of the Clock of the context e was created in.
+func (e *Event) now() time.Time {
+	if e == nil {
+		return SystemClock.Now()
+	}
+	return e.clock.Now()
+}
+
+func (e *Event) setLocked(attr slog.Attr) {
+	if i, ok := e.index[attr.Key]; ok {
+		e.attrs[i] = attr
+		return
+	}
+	e.index[attr.Key] = len(e.attrs)
+	e.attrs = append(e.attrs, attr)
+}
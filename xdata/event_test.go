@@ -0,0 +1,63 @@
+package xdata
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+)
+
+func TestEvent(t *testing.T) {
+	ctx, e := WithEvent(context.Background())
+	assert.Same(t, e, ContextEvent(ctx))
+
+	e.Add(slog.String("route", "/users"), slog.Int("status", 0))
+	e.Count("queries", 1)
+	e.AddDuration("db", time.Second)
+	e.Add(slog.Int("status", 200))
+	e.Count("queries", 2)
+	e.AddDuration("db", time.Second)
+	e.Count("route", 1)
+
+	assert.Equal(t, []slog.Attr{
+		slog.Int64("route", 1),
+		slog.Int("status", 200),
+		slog.Int64("queries", 3),
+		slog.Duration("db", 2*time.Second),
+	}, e.Attrs())
+}
+
+func TestEvent_Concurrent(t *testing.T) {
+	_, e := WithEvent(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				e.Count("calls", 1)
+				e.Time("work")()
+				e.Add(slog.Bool("done", true))
+			}
+		}()
+	}
+	wg.Wait()
+
+	attrs := e.Attrs()
+	assert.Len(t, attrs, 3)
+	assert.Equal(t, slog.Int64("calls", 1000), attrs[0])
+}
+
+func TestEvent_Nil(t *testing.T) {
+	e := ContextEvent(context.Background())
+	assert.Nil(t, e)
+	e.Add(slog.String("k", "v"))
+	e.Count("n", 1)
+	e.Time("t")()
+	assert.Nil(t, e.Attrs())
+	assert.Zero(t, e.Elapsed())
+	assert.Nil(t, ContextEvent(nil))
+}
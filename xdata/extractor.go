@@ -0,0 +1,81 @@
+package xdata
+
+import (
+	"context"
+	"runtime/pprof"
+
+	"go.opentelemetry.io/otel/baggage"
+	"golang.org/x/exp/slog"
+)
+
+// DeadlineKey is the attr key used by DeadlineExtractor.
+const DeadlineKey = "deadline_remaining"
+
+// Extractor derives attrs from values of a context other than the attrs bound with WithAttrs,
+// e.g. an auth principal or a tenant stored by another package.
+type Extractor func(ctx context.Context) []slog.Attr
+
+// Grouped returns an extractor that nests the attrs returned by e in a group named group.
+func Grouped(group string, e Extractor) Extractor {
+	if group == "" {
+		return e
+	}
+	return func(ctx context.Context) []slog.Attr {
+		attrs := e(ctx)
+		if len(attrs) == 0 {
+			return nil
+		}
+		return []slog.Attr{{Key: group, Value: slog.GroupValue(attrs...)}}
+	}
+}
+
+// BaggageExtractor returns an extractor of the OpenTelemetry baggage members named keys.
+// Members that are not listed are never extracted.
+func BaggageExtractor(keys ...string) Extractor {
+	return func(ctx context.Context) []slog.Attr {
+		b := baggage.FromContext(ctx)
+		if b.Len() == 0 {
+			return nil
+		}
+		var attrs []slog.Attr
+		for _, key := range keys {
+			if member := b.Member(key); member.Key() != "" {
+				attrs = append(attrs, slog.String(key, member.Value()))
+			}
+		}
+		return attrs
+	}
+}
+
+// DeadlineExtractor returns an extractor of the time remaining until the deadline of the context,
+// according to the Clock bound with the context.
+func DeadlineExtractor() Extractor {
+	return func(ctx context.Context) []slog.Attr {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return nil
+		}
+		return []slog.Attr{slog.Duration(DeadlineKey, deadline.Sub(Now(ctx)))}
+	}
+}
+
+// PprofLabelsExtractor returns an extractor of the runtime/pprof labels named keys.
+// If no keys are given, all labels are extracted.
+func PprofLabelsExtractor(keys ...string) Extractor {
+	return func(ctx context.Context) []slog.Attr {
+		var attrs []slog.Attr
+		if len(keys) == 0 {
+			pprof.ForLabels(ctx, func(key, value string) bool {
+				attrs = append(attrs, slog.String(key, value))
+				return true
+			})
+			return attrs
+		}
+		for _, key := range keys {
+			if value, ok := pprof.Label(ctx, key); ok {
+				attrs = append(attrs, slog.String(key, value))
+			}
+		}
+		return attrs
+	}
+}
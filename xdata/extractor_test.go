@@ -0,0 +1,64 @@
+package xdata
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+	"time"
+
+	"github.com/galecore/xslog/xtesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/baggage"
+	"golang.org/x/exp/slog"
+)
+
+func TestGrouped(t *testing.T) {
+	e := func(ctx context.Context) []slog.Attr {
+		return ContextAttrs(ctx)
+	}
+	ctx := WithAttrs(context.Background(), slog.String("key", "value"))
+
+	assert.Equal(t, []slog.Attr{slog.Group("g", slog.String("key", "value"))}, Grouped("g", e)(ctx))
+	assert.Equal(t, []slog.Attr{slog.String("key", "value")}, Grouped("", e)(ctx))
+	assert.Nil(t, Grouped("g", e)(context.Background()))
+}
+
+func TestBaggageExtractor(t *testing.T) {
+	tenant, err := baggage.NewMember("tenant", "acme")
+	require.NoError(t, err)
+	secret, err := baggage.NewMember("secret", "value")
+	require.NoError(t, err)
+	b, err := baggage.New(tenant, secret)
+	require.NoError(t, err)
+
+	e := BaggageExtractor("tenant", "missing")
+	assert.Nil(t, e(context.Background()))
+	assert.Equal(t, []slog.Attr{slog.String("tenant", "acme")}, e(baggage.ContextWithBaggage(context.Background(), b)))
+}
+
+func TestDeadlineExtractor(t *testing.T) {
+	e := DeadlineExtractor()
+	assert.Nil(t, e(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	attrs := e(ctx)
+	require.Len(t, attrs, 1)
+	assert.Equal(t, DeadlineKey, attrs[0].Key)
+	assert.InDelta(t, time.Hour, attrs[0].Value.Duration(), float64(time.Minute))
+
+	now := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	ctx = WithClock(context.Background(), xtesting.NewFakeClock(now, 0))
+	ctx, cancel = context.WithDeadline(ctx, now.Add(30*time.Second))
+	defer cancel()
+	assert.Equal(t, []slog.Attr{slog.Duration(DeadlineKey, 30*time.Second)}, e(ctx))
+}
+
+func TestPprofLabelsExtractor(t *testing.T) {
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels("route", "/users", "tenant", "acme"))
+
+	assert.Equal(t, []slog.Attr{slog.String("tenant", "acme")}, PprofLabelsExtractor("tenant", "missing")(ctx))
+	assert.ElementsMatch(t, []slog.Attr{slog.String("route", "/users"), slog.String("tenant", "acme")}, PprofLabelsExtractor()(ctx))
+	assert.Nil(t, PprofLabelsExtractor()(context.Background()))
+}
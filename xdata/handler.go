@@ -3,15 +3,67 @@ package xdata
 import (
 	"context"
 
+	"github.com/jba/slog/withsupport"
 	"golang.org/x/exp/slog"
 )
 
+// HandlerOptions configures how a Handler adds context attrs to records.
+type HandlerOptions struct {
+	// MergePolicy resolves context attrs that share a key. Defaults to MergeAppend.
+	MergePolicy MergePolicy
+	// RecordShadowsContext drops context attrs whose key is also set on the record itself.
+	RecordShadowsContext bool
+	// Extractors add attrs derived from other context values after the attrs bound with WithAttrs.
+	Extractors []Extractor
+	// Placement chooses where context attrs are added to records. Defaults to PlaceAppend.
+	// PlaceTopLevel and PlaceGroup rebuild the handler chain for every record logged within a group,
+	// which is considerably slower than the other placements.
+	Placement Placement
+	// Group names the group of context attrs under PlaceGroup. Defaults to DefaultGroup.
+	Group string
+}
+
 func NewHandler(h slog.Handler) *Handler {
-	return &Handler{h: h}
+	return &Handler{h: h, root: h}
+}
+
+func NewHandlerWithOptions(h slog.Handler, opts *HandlerOptions) *Handler {
+	handler := &Handler{h: h, root: h}
+	if opts != nil {
+		handler.opts = *opts
+	}
+	if handler.opts.Group == "" {
+		handler.opts.Group = DefaultGroup
+	}
+	return handler
+}
+
+// ContextAttrsHandler is implemented by handlers that report whether they, or the handlers they wrap,
+// add the attrs bound with WithAttrs to records, like Handler. Wrapping handlers should implement it,
+// so that xslog does not add the attrs a second time.
+type ContextAttrsHandler interface {
+	HandlesContextAttrs() bool
+}
+
+// HandlesContextAttrs reports whether h adds the attrs bound with WithAttrs to records by itself.
+func HandlesContextAttrs(h slog.Handler) bool {
+	handler, ok := h.(ContextAttrsHandler)
+	return ok && handler.HandlesContextAttrs()
 }
 
 type Handler struct {
-	h slog.Handler
+	h    slog.Handler
+	opts HandlerOptions
+
+	// root is h before the first WithGroup call and goa records the calls made since,
+	// so that context attrs can be added outside of the groups.
+	root slog.Handler
+	goa  *withsupport.GroupOrAttrs
+}
+
+// HandlesContextAttrs reports true, since h adds the attrs bound with WithAttrs to every record.
+func (h *Handler) HandlesContextAttrs() bool {
+	return true
 }
 
 func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
@@ -19,14 +71,57 @@ func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
 }
 
 func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
-	record.AddAttrs(ContextAttrs(ctx)...)
-	return h.h.Handle(ctx, record)
+	attrs := ContextAttrs(ctx)
+	if len(h.opts.Extractors) > 0 && ctx != nil {
+		attrs = append([]slog.Attr(nil), attrs...)
+		for _, extract := range h.opts.Extractors {
+			attrs = append(attrs, extract(ctx)...)
+		}
+	}
+	attrs = mergeAttrs(attrs, h.opts.MergePolicy)
+	if h.opts.RecordShadowsContext {
+		attrs = shadowAttrs(attrs, record)
+	}
+	attrs = resolveLazyAttrs(attrs)
+	if len(attrs) == 0 {
+		return h.h.Handle(ctx, record)
+	}
+
+	switch h.opts.Placement {
+	case PlacePrepend:
+		return h.h.Handle(ctx, prependAttrs(record, attrs))
+	case PlaceTopLevel:
+		return h.handleTopLevel(ctx, record, attrs)
+	case PlaceGroup:
+		group := slog.Attr{Key: h.opts.Group, Value: slog.GroupValue(attrs...)}
+		return h.handleTopLevel(ctx, record, []slog.Attr{group})
+	default:
+		record.AddAttrs(attrs...)
+		return h.h.Handle(ctx, record)
+	}
+}
+
+// WithExtractors returns a copy of h that also adds the attrs returned by extractors to every record.
+// Use Grouped to nest the output of an extractor in its own group.
+func (h *Handler) WithExtractors(extractors ...Extractor) *Handler {
+	opts := h.opts
+	opts.Extractors = append(append([]Extractor(nil), h.opts.Extractors...), extractors...)
+	return &Handler{h: h.h, opts: opts, root: h.root, goa: h.goa}
 }
 
 func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return &Handler{h: h.h.WithAttrs(attrs)}
+	handler := &Handler{h: h.h.WithAttrs(attrs), opts: h.opts, root: h.root, goa: h.goa}
+	if h.goa == nil {
+		handler.root = handler.h
+	} else {
+		handler.goa = h.goa.WithAttrs(attrs)
+	}
+	return handler
 }
 
 func (h *Handler) WithGroup(name string) slog.Handler {
-	return &Handler{h: h.h.WithGroup(name)}
+	if name == "" {
+		return h
+	}
+	return &Handler{h: h.h.WithGroup(name), opts: h.opts, root: h.root, goa: h.goa.WithGroup(name)}
 }
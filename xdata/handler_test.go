@@ -1,10 +1,15 @@
 package xdata
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/galecore/xslog/util"
+	"github.com/galecore/xslog/xslogtest"
 	"github.com/galecore/xslog/xtesting"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/exp/slog"
@@ -64,3 +69,175 @@ func TestHandler_WithGroup(t *testing.T) {
 	}
 	assert.Equal(t, "DEBUG: test [l1.int=1 l1.key=value]INFO: test [l1.int=1 l1.key=value]WARN: test [l1.int=1 l1.key=value]ERROR: test [l1.int=1 l1.key=value]", l.B.String())
 }
+
+func BenchmarkHandler_Handle(b *testing.B) {
+	for _, depth := range []int{0, 1, 10} {
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			handler := NewHandler(slog.NewJSONHandler(io.Discard, nil))
+			ctx := context.Background()
+			for j := 0; j < depth; j++ {
+				ctx = WithAttrs(ctx, slog.Int("depth", j))
+			}
+			record := slog.NewRecord(time.Time{}, slog.LevelInfo, "test", 0)
+			record.AddAttrs(slog.String("key", "value"))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = handler.Handle(ctx, record)
+			}
+		})
+	}
+}
+
+func TestHandler_MergePolicy(t *testing.T) {
+	ctx := WithAttrs(context.Background(), slog.String("user", "a"))
+	ctx = WithAttrs(ctx, slog.String("user", "b"))
+
+	t.Run("default", func(t *testing.T) {
+		l := util.NewBufferedLogger()
+		assert.NoError(t, NewHandler(xtesting.NewHandler(l)).Handle(ctx, slog.Record{Message: "test"}))
+		assert.Equal(t, "INFO: test [user=a user=b]", l.B.String())
+	})
+
+	t.Run("last wins", func(t *testing.T) {
+		l := util.NewBufferedLogger()
+		h := NewHandlerWithOptions(xtesting.NewHandler(l), &HandlerOptions{MergePolicy: MergeLastWins})
+		assert.NoError(t, h.WithGroup("g").Handle(ctx, slog.Record{Message: "test"}))
+		assert.Equal(t, "INFO: test [g.user=b]", l.B.String())
+	})
+
+	t.Run("collect", func(t *testing.T) {
+		var buffer bytes.Buffer
+		h := NewHandlerWithOptions(slog.NewJSONHandler(&buffer, nil), &HandlerOptions{MergePolicy: MergeCollect})
+		assert.NoError(t, h.Handle(ctx, slog.Record{Message: "test"}))
+		assert.Equal(t, `{"level":"INFO","msg":"test","user":["a","b"]}`+"\n", buffer.String())
+	})
+
+	t.Run("record shadows context", func(t *testing.T) {
+		l := util.NewBufferedLogger()
+		h := NewHandlerWithOptions(xtesting.NewHandler(l), &HandlerOptions{MergePolicy: MergeLastWins, RecordShadowsContext: true})
+		record := slog.Record{Message: "test"}
+		record.AddAttrs(slog.String("user", "record"))
+		assert.NoError(t, h.WithAttrs([]slog.Attr{slog.Int("int", 1)}).Handle(ctx, record))
+		assert.Equal(t, "INFO: test [int=1 user=record]", l.B.String())
+	})
+}
+
+type tenantKey struct{}
+
+func TestHandler_WithExtractors(t *testing.T) {
+	tenant := func(ctx context.Context) []slog.Attr {
+		if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+			return []slog.Attr{slog.String("tenant", tenant)}
+		}
+		return nil
+	}
+	ctx := WithAttrs(context.Background(), slog.String("key", "value"))
+	ctx = context.WithValue(ctx, tenantKey{}, "acme")
+
+	t.Run("flat", func(t *testing.T) {
+		l := util.NewBufferedLogger()
+		h := NewHandler(xtesting.NewHandler(l)).WithExtractors(tenant)
+		assert.NoError(t, h.Handle(ctx, slog.Record{Message: "test"}))
+		assert.NoError(t, h.Handle(context.Background(), slog.Record{Message: "test"}))
+		assert.Equal(t, "INFO: test [key=value tenant=acme]INFO: test []", l.B.String())
+	})
+
+	t.Run("grouped", func(t *testing.T) {
+		var buffer bytes.Buffer
+		h := NewHandlerWithOptions(slog.NewJSONHandler(&buffer, nil), &HandlerOptions{
+			Extractors: []Extractor{Grouped("auth", tenant)},
+		})
+		assert.NoError(t, h.Handle(ctx, slog.Record{Message: "test"}))
+		assert.Equal(t, `{"level":"INFO","msg":"test","key":"value","auth":{"tenant":"acme"}}`+"\n", buffer.String())
+	})
+
+	t.Run("does not modify context attrs", func(t *testing.T) {
+		h := NewHandler(xtesting.NewHandler(util.NewBufferedLogger())).WithExtractors(tenant)
+		assert.NoError(t, h.Handle(ctx, slog.Record{Message: "test"}))
+		assert.Equal(t, []slog.Attr{slog.String("key", "value")}, ContextAttrs(ctx))
+	})
+}
+
+func TestHandler_Placement(t *testing.T) {
+	ctx := WithAttrs(context.Background(), slog.String("tenant", "acme"), slog.Int("shard", 7))
+	for _, tt := range []struct {
+		name      string
+		placement Placement
+		want      string
+	}{
+		{name: "append", placement: PlaceAppend, want: "INFO: test [a=1 g1.b=2 g1.g2.c=3 g1.g2.tenant=acme g1.g2.shard=7]"},
+		{name: "prepend", placement: PlacePrepend, want: "INFO: test [a=1 g1.b=2 g1.g2.tenant=acme g1.g2.shard=7 g1.g2.c=3]"},
+		{name: "top level", placement: PlaceTopLevel, want: "INFO: test [a=1 tenant=acme shard=7 g1.b=2 g1.g2.c=3]"},
+		{name: "group", placement: PlaceGroup, want: "INFO: test [a=1 ctx=[tenant=acme shard=7] g1.b=2 g1.g2.c=3]"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			l := util.NewBufferedLogger()
+			logger := slog.New(NewHandlerWithOptions(xtesting.NewHandler(l), &HandlerOptions{Placement: tt.placement})).
+				With("a", 1).WithGroup("g1").With("b", 2).WithGroup("g2")
+			logger.InfoCtx(ctx, "test", "c", 3)
+			assert.Equal(t, tt.want, l.B.String())
+		})
+	}
+
+	t.Run("without groups", func(t *testing.T) {
+		l := util.NewBufferedLogger()
+		logger := slog.New(NewHandlerWithOptions(xtesting.NewHandler(l), &HandlerOptions{Placement: PlaceGroup, Group: "meta"})).
+			With("a", 1)
+		logger.InfoCtx(ctx, "test", "c", 3)
+		assert.Equal(t, "INFO: test [a=1 c=3 meta=[tenant=acme shard=7]]", l.B.String())
+	})
+}
+
+func TestHandler_ContextGroup(t *testing.T) {
+	l := util.NewBufferedLogger()
+	logger := slog.New(NewHandler(xtesting.NewHandler(l)))
+	ctx := WithGroup(context.Background(), "http", slog.String("method", "GET"), slog.String("path", "/"))
+	ctx = WithAttrs(ctx, slog.String("tenant", "acme"))
+	ctx = WithGroup(ctx, "empty")
+
+	logger.InfoCtx(ctx, "test")
+	assert.Equal(t, "INFO: test [http=[method=GET path=/] tenant=acme]", l.B.String())
+}
+
+func TestHandler_Conformance(t *testing.T) {
+	ctx := WithAttrs(context.Background(), slog.String("ctx", "v"))
+	// Context attrs placed inside the groups from WithGroup keep those groups from being empty.
+	inGroups := []string{"empty-with-group", "empty-nested-with-group"}
+	for _, tt := range []struct {
+		name      string
+		placement Placement
+		skip      []string
+	}{
+		{name: "append", placement: PlaceAppend, skip: inGroups},
+		{name: "prepend", placement: PlacePrepend, skip: inGroups},
+		{name: "top level", placement: PlaceTopLevel},
+		{name: "group", placement: PlaceGroup},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			xslogtest.Run(t, func(t *testing.T) (slog.Handler, func() []map[string]any) {
+				r := xtesting.NewRecorder()
+				h := NewHandlerWithOptions(r, &HandlerOptions{Placement: tt.placement})
+				return &contextHandler{Handler: h, ctx: ctx}, r.Results
+			}, &xslogtest.Options{Skip: tt.skip})
+		})
+	}
+}
+
+// contextHandler passes a fixed context to the wrapped handler, since loggers pass none in tests.
+type contextHandler struct {
+	slog.Handler
+	ctx context.Context
+}
+
+func (h *contextHandler) Handle(_ context.Context, record slog.Record) error {
+	return h.Handler.Handle(h.ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs), ctx: h.ctx}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name), ctx: h.ctx}
+}
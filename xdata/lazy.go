@@ -0,0 +1,73 @@
+package xdata
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/exp/slog"
+)
+
+// WithLazyAttrs returns a new context that is bound with an attr named key whose value is computed by fn
+// and based on parent ctx. fn is called at most once, when the first record carrying the attr is handled,
+// and its result is shared by all records logged with ctx and the contexts derived from it.
+// If fn panics, the attr holds an error describing the panic instead.
+func WithLazyAttrs(ctx context.Context, key string, fn func() slog.Value) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	return WithAttrs(ctx, slog.Any(key, &lazyValue{key: key, fn: fn}))
+}
+
+// lazyValue is a slog.LogValuer that memoizes the value returned by fn.
+type lazyValue struct {
+	key  string
+	fn   func() slog.Value
+	once sync.Once
+	v    slog.Value
+}
+
+func (l *lazyValue) LogValue() slog.Value {
+	l.once.Do(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				l.v = slog.AnyValue(fmt.Errorf("lazy attr %q panicked: %v", l.key, r))
+			}
+		}()
+		l.v = l.fn().Resolve()
+	})
+	return l.v
+}
+
+// resolveLazyAttrs returns attrs with the values of lazy attrs resolved,
+// so that they are rendered correctly by handlers that do not resolve slog.LogValuer values.
+// It returns attrs itself if it holds no lazy attrs.
+func resolveLazyAttrs(attrs []slog.Attr) []slog.Attr {
+	var resolved []slog.Attr
+	for i, attr := range attrs {
+		if attr.Value.Kind() != slog.KindLogValuer {
+			continue
+		}
+		lazy, ok := attr.Value.LogValuer().(*lazyValue)
+		if !ok {
+			continue
+		}
+		if resolved == nil {
+			resolved = append([]slog.Attr(nil), attrs...)
+		}
+		resolved[i].Value = lazy.LogValue()
+	}
+	if resolved == nil {
+		return attrs
+	}
+	return resolved
+}
+
+// isLazy reports whether v is the value of a lazy attr.
+func isLazy(v slog.Value) bool {
+	if v.Kind() != slog.KindLogValuer {
+		return false
+	}
+	_, ok := v.LogValuer().(*lazyValue)
+	return ok
+}
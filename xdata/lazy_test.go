@@ -0,0 +1,82 @@
+package xdata
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/galecore/xslog/util"
+	"github.com/galecore/xslog/xtesting"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+)
+
+func TestWithLazyAttrs(t *testing.T) {
+	t.Run("evaluated once when handled", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(NewHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		})))
+		calls := 0
+		ctx := WithLazyAttrs(context.Background(), "org", func() slog.Value {
+			calls++
+			return slog.StringValue("acme")
+		})
+		child := WithAttrs(ctx, slog.Int("n", 1))
+
+		logger.DebugCtx(ctx, "filtered")
+		assert.Equal(t, 0, calls)
+
+		logger.InfoCtx(ctx, "first")
+		logger.InfoCtx(child, "second")
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, "level=INFO msg=first org=acme\nlevel=INFO msg=second org=acme n=1\n", buf.String())
+	})
+
+	t.Run("shadowed", func(t *testing.T) {
+		l := util.NewBufferedLogger()
+		logger := slog.New(NewHandlerWithOptions(xtesting.NewHandler(l), &HandlerOptions{MergePolicy: MergeFirstWins}))
+		ctx := WithAttrs(context.Background(), slog.String("org", "eager"))
+		ctx = WithLazyAttrs(ctx, "org", func() slog.Value {
+			t.Fatal("shadowed lazy attr evaluated")
+			return slog.Value{}
+		})
+
+		logger.InfoCtx(ctx, "test")
+		assert.Equal(t, "INFO: test [org=eager]", l.B.String())
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		l := util.NewBufferedLogger()
+		logger := slog.New(NewHandler(xtesting.NewHandler(l)))
+		ctx := WithLazyAttrs(context.Background(), "org", func() slog.Value {
+			panic("cache down")
+		})
+
+		logger.InfoCtx(ctx, "test")
+		assert.Equal(t, `INFO: test [org=lazy attr "org" panicked: cache down]`, l.B.String())
+	})
+
+	t.Run("nil func", func(t *testing.T) {
+		ctx := context.Background()
+		assert.Equal(t, ctx, WithLazyAttrs(ctx, "org", nil))
+	})
+}
+
+func BenchmarkWithLazyAttrs_Filtered(b *testing.B) {
+	logger := slog.New(NewHandler(slog.NewTextHandler(io.Discard, nil)))
+	ctx := WithLazyAttrs(context.Background(), "flags", func() slog.Value {
+		return slog.StringValue(fmt.Sprint(make([]int, 1000)))
+	})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.DebugCtx(ctx, "filtered")
+	}
+}
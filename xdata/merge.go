@@ -0,0 +1,109 @@
+package xdata
+
+import (
+	"golang.org/x/exp/slog"
+)
+
+// MergePolicy resolves context attrs that were bound with the same key more than once,
+// e.g. by nested calls of WithAttrs(ctx, slog.String("user", ...)).
+type MergePolicy int
+
+const (
+	// MergeAppend keeps every attr, so repeated keys are emitted repeatedly.
+	MergeAppend MergePolicy = iota
+	// MergeLastWins keeps only the value bound last, at the position of the first occurrence.
+	MergeLastWins
+	// MergeFirstWins keeps only the value bound first.
+	MergeFirstWins
+	// MergeCollect replaces repeated attrs with a single attr holding a slice of all their values.
+	MergeCollect
+)
+
+// mergeAttrs applies policy to attrs. It returns attrs itself if no key is repeated.
+func mergeAttrs(attrs []slog.Attr, policy MergePolicy) []slog.Attr {
+	if policy == MergeAppend || !hasRepeatedKeys(attrs) {
+		return attrs
+	}
+
+	merged := make([]slog.Attr, 0, len(attrs))
+	index := make(map[string]int, len(attrs))
+	var collected map[string][]any
+	for _, attr := range attrs {
+		i, seen := index[attr.Key]
+		if !seen {
+			index[attr.Key] = len(merged)
+			merged = append(merged, attr)
+			continue
+		}
+		switch policy {
+		case MergeLastWins:
+			merged[i] = attr
+		case MergeCollect:
+			if collected == nil {
+				collected = make(map[string][]any)
+			}
+			if _, ok := collected[attr.Key]; !ok {
+				collected[attr.Key] = []any{merged[i].Value.Resolve().Any()}
+			}
+			collected[attr.Key] = append(collected[attr.Key], attr.Value.Resolve().Any())
+		}
+	}
+	for key, values := range collected {
+		merged[index[key]] = slog.Any(key, values)
+	}
+	return merged
+}
+
+// shadowAttrs drops attrs whose key is set on record.
+func shadowAttrs(attrs []slog.Attr, record slog.Record) []slog.Attr {
+	if len(attrs) == 0 || record.NumAttrs() == 0 {
+		return attrs
+	}
+	var shadowed []slog.Attr
+	for i, attr := range attrs {
+		if !recordHasKey(record, attr.Key) {
+			if shadowed != nil {
+				shadowed = append(shadowed, attr)
+			}
+			continue
+		}
+		if shadowed == nil {
+			shadowed = make([]slog.Attr, i, len(attrs))
+			copy(shadowed, attrs[:i])
+		}
+	}
+	if shadowed == nil {
+		return attrs
+	}
+	return shadowed
+}
+
+func recordHasKey(record slog.Record, key string) bool {
+	found := false
+	record.Attrs(func(attr slog.Attr) bool {
+		found = attr.Key == key
+		return !found
+	})
+	return found
+}
+
+func hasRepeatedKeys(attrs []slog.Attr) bool {
+	if len(attrs) > 16 {
+		seen := make(map[string]struct{}, len(attrs))
+		for _, attr := range attrs {
+			if _, ok := seen[attr.Key]; ok {
+				return true
+			}
+			seen[attr.Key] = struct{}{}
+		}
+		return false
+	}
+	for i := 1; i < len(attrs); i++ {
+		for j := 0; j < i; j++ {
+			if attrs[i].Key == attrs[j].Key {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package xdata
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+)
+
+func TestMergeAttrs(t *testing.T) {
+	attrs := []slog.Attr{
+		slog.String("user", "a"),
+		slog.Int("int", 1),
+		slog.String("user", "b"),
+		slog.String("user", "c"),
+	}
+	tests := []struct {
+		name   string
+		policy MergePolicy
+		want   []slog.Attr
+	}{
+		{
+			name:   "append",
+			policy: MergeAppend,
+			want:   attrs,
+		},
+		{
+			name:   "last wins",
+			policy: MergeLastWins,
+			want:   []slog.Attr{slog.String("user", "c"), slog.Int("int", 1)},
+		},
+		{
+			name:   "first wins",
+			policy: MergeFirstWins,
+			want:   []slog.Attr{slog.String("user", "a"), slog.Int("int", 1)},
+		},
+		{
+			name:   "collect",
+			policy: MergeCollect,
+			want:   []slog.Attr{slog.Any("user", []any{"a", "b", "c"}), slog.Int("int", 1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mergeAttrs(attrs, tt.policy))
+		})
+	}
+
+	t.Run("no repeated keys", func(t *testing.T) {
+		unique := []slog.Attr{slog.String("a", "1"), slog.String("b", "2")}
+		assert.Equal(t, unique, mergeAttrs(unique, MergeLastWins))
+	})
+}
+
+func TestShadowAttrs(t *testing.T) {
+	attrs := []slog.Attr{slog.String("user", "a"), slog.Int("int", 1), slog.String("other", "b")}
+
+	record := slog.Record{}
+	assert.Equal(t, attrs, shadowAttrs(attrs, record))
+
+	record.AddAttrs(slog.String("user", "record"))
+	assert.Equal(t, []slog.Attr{slog.Int("int", 1), slog.String("other", "b")}, shadowAttrs(attrs, record))
+
+	record.AddAttrs(slog.String("other", "record"))
+	assert.Equal(t, []slog.Attr{slog.Int("int", 1)}, shadowAttrs(attrs, record))
+}
+
+func TestHasRepeatedKeys(t *testing.T) {
+	assert.False(t, hasRepeatedKeys(nil))
+	assert.False(t, hasRepeatedKeys([]slog.Attr{slog.Int("a", 1), slog.Int("b", 1)}))
+	assert.True(t, hasRepeatedKeys([]slog.Attr{slog.Int("a", 1), slog.Int("b", 1), slog.Int("a", 2)}))
+
+	many := make([]slog.Attr, 32)
+	for i := range many {
+		many[i] = slog.Int(fmt.Sprint(i), i)
+	}
+	assert.False(t, hasRepeatedKeys(many))
+	assert.True(t, hasRepeatedKeys(append(many, slog.Int("7", 7))))
+}
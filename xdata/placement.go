@@ -0,0 +1,53 @@
+package xdata
+
+import (
+	"context"
+
+	"golang.org/x/exp/slog"
+)
+
+// DefaultGroup is the group that holds context attrs under PlaceGroup when HandlerOptions.Group is not set.
+const DefaultGroup = "ctx"
+
+// Placement chooses where a Handler adds context attrs to a record.
+type Placement int
+
+const (
+	// PlaceAppend adds context attrs after the attrs of the record, inside the groups opened with WithGroup.
+	PlaceAppend Placement = iota
+	// PlacePrepend adds context attrs before the attrs of the record, inside the groups opened with WithGroup.
+	PlacePrepend
+	// PlaceTopLevel adds context attrs at the top level of the record, regardless of the groups opened with WithGroup.
+	PlaceTopLevel
+	// PlaceGroup adds context attrs to a group named HandlerOptions.Group at the top level of the record.
+	PlaceGroup
+)
+
+// handleTopLevel passes record to the handler that the groups and attrs of h are applied to only after attrs,
+// so that attrs are not nested in the groups opened with WithGroup.
+func (h *Handler) handleTopLevel(ctx context.Context, record slog.Record, attrs []slog.Attr) error {
+	if h.goa == nil {
+		record.AddAttrs(attrs...)
+		return h.h.Handle(ctx, record)
+	}
+	handler := h.root.WithAttrs(attrs)
+	for _, goa := range h.goa.Collect() {
+		if goa.Group != "" {
+			handler = handler.WithGroup(goa.Group)
+		} else {
+			handler = handler.WithAttrs(goa.Attrs)
+		}
+	}
+	return handler.Handle(ctx, record)
+}
+
+// prependAttrs returns a copy of record whose attrs start with attrs.
+func prependAttrs(record slog.Record, attrs []slog.Attr) slog.Record {
+	r := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	r.AddAttrs(attrs...)
+	record.Attrs(func(a slog.Attr) bool {
+		r.AddAttrs(a)
+		return true
+	})
+	return r
+}
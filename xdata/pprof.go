@@ -0,0 +1,46 @@
+package xdata
+
+import (
+	"context"
+	"runtime/pprof"
+
+	"golang.org/x/exp/slog"
+)
+
+// PprofLabels returns the runtime/pprof labels of the attrs of ctx named keys, with values formatted as strings.
+// If no keys are given, all top-level attrs are used except lazy ones, which are only computed when named in keys.
+// Groups are skipped, and for attrs that share a key the last one wins, matching how pprof merges labels.
+func PprofLabels(ctx context.Context, keys ...string) pprof.LabelSet {
+	var args []string
+	for _, attr := range ContextAttrs(ctx) {
+		if !selectedKey(keys, attr.Key) || len(keys) == 0 && isLazy(attr.Value) {
+			continue
+		}
+		v := attr.Value.Resolve()
+		if v.Kind() == slog.KindGroup {
+			continue
+		}
+		args = append(args, attr.Key, v.String())
+	}
+	return pprof.Labels(args...)
+}
+
+// DoWithPprofLabels calls f with a copy of ctx that carries the runtime/pprof labels of the attrs named keys,
+// so that profile samples taken while f runs can be joined with logs on the same keys.
+// Use PprofLabelsExtractor to add the labels of a context back to its records.
+func DoWithPprofLabels(ctx context.Context, keys []string, f func(ctx context.Context)) {
+	pprof.Do(ctx, PprofLabels(ctx, keys...), f)
+}
+
+// selectedKey reports whether key is one of keys, treating no keys as selecting all.
+func selectedKey(keys []string, key string) bool {
+	if len(keys) == 0 {
+		return true
+	}
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
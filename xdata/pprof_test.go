@@ -0,0 +1,67 @@
+package xdata
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/galecore/xslog/util"
+	"github.com/galecore/xslog/xtesting"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+)
+
+func contextLabels(ctx context.Context) map[string]string {
+	labels := make(map[string]string)
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		labels[key] = value
+		return true
+	})
+	return labels
+}
+
+func TestPprofLabels(t *testing.T) {
+	ctx := WithAttrs(context.Background(),
+		slog.String("tenant", "acme"),
+		slog.Int("shard", 7),
+		slog.Group("http", slog.String("method", "GET")),
+	)
+	ctx = WithAttrs(ctx, slog.String("tenant", "globex"))
+
+	labeled := pprof.WithLabels(context.Background(), PprofLabels(ctx))
+	assert.Equal(t, map[string]string{"tenant": "globex", "shard": "7"}, contextLabels(labeled))
+	labeled = pprof.WithLabels(context.Background(), PprofLabels(ctx, "shard", "missing"))
+	assert.Equal(t, map[string]string{"shard": "7"}, contextLabels(labeled))
+}
+
+func TestPprofLabels_LazyAttrs(t *testing.T) {
+	var calls int
+	ctx := WithLazyAttrs(context.Background(), "plan", func() slog.Value {
+		calls++
+		return slog.StringValue("pro")
+	})
+	ctx = WithAttrs(ctx, slog.String("tenant", "acme"))
+
+	DoWithPprofLabels(ctx, nil, func(ctx context.Context) {
+		assert.Equal(t, map[string]string{"tenant": "acme"}, contextLabels(ctx))
+	})
+	assert.Zero(t, calls, "lazy attrs are left out unless selected")
+
+	labeled := pprof.WithLabels(context.Background(), PprofLabels(ctx, "plan"))
+	assert.Equal(t, map[string]string{"plan": "pro"}, contextLabels(labeled))
+	assert.Equal(t, 1, calls)
+}
+
+func TestDoWithPprofLabels(t *testing.T) {
+	l := util.NewBufferedLogger()
+	logger := slog.New(NewHandlerWithOptions(xtesting.NewHandler(l), &HandlerOptions{
+		Extractors: []Extractor{Grouped("pprof", PprofLabelsExtractor("tenant", "route"))},
+	}))
+	ctx := WithAttrs(context.Background(), slog.String("tenant", "acme"), slog.String("route", "/users"))
+
+	DoWithPprofLabels(ctx, []string{"tenant", "route"}, func(ctx context.Context) {
+		assert.Equal(t, map[string]string{"tenant": "acme", "route": "/users"}, contextLabels(ctx))
+		logger.InfoCtx(ctx, "test")
+	})
+	assert.Equal(t, "INFO: test [tenant=acme route=/users pprof=[tenant=acme route=/users]]", l.B.String())
+}
@@ -0,0 +1,229 @@
+package xdata
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// PropagationKey is the header and metadata key that carries propagated attrs between services.
+const PropagationKey = "x-log-attrs"
+
+// Limits applied when the corresponding Propagator fields are not set. They match the limits of W3C baggage.
+const (
+	DefaultMaxPropagatedBytes   = 8192
+	DefaultMaxPropagatedMembers = 180
+)
+
+// Type hints of propagated values, stored in the "t" property of a list member.
+const (
+	typeHintKey      = "t"
+	typeHintInt      = "i"
+	typeHintUint     = "u"
+	typeHintFloat    = "f"
+	typeHintBool     = "b"
+	typeHintDuration = "d"
+	typeHintTime     = "ts"
+)
+
+// Propagator serializes context attrs into a W3C-baggage-compatible list
+// (e.g. "tenant=acme,retries=3;t=i") and restores them on the receiving side.
+// Values are percent-encoded; values that are not strings carry a type hint property.
+type Propagator struct {
+	// Keys lists the keys of attrs that are encoded and accepted when decoding.
+	// If empty, nothing is propagated, so that attrs never leak to or are injected from other services by accident.
+	Keys []string
+	// MaxBytes caps the encoded length. Defaults to DefaultMaxPropagatedBytes.
+	MaxBytes int
+	// MaxMembers caps the number of propagated attrs. Defaults to DefaultMaxPropagatedMembers.
+	MaxMembers int
+}
+
+// NewPropagator returns a Propagator of the attrs named keys with default limits.
+func NewPropagator(keys ...string) *Propagator {
+	return &Propagator{Keys: keys}
+}
+
+// Encode returns the encoded attrs of ctx that are selected by p.
+// Attrs that would exceed the size limits are dropped. Groups are never propagated.
+func (p *Propagator) Encode(ctx context.Context) string {
+	var (
+		b       strings.Builder
+		members int
+	)
+	for _, attr := range ContextAttrs(ctx) {
+		if members >= p.maxMembers() {
+			break
+		}
+		// Keys are checked before values are resolved, so that unselected lazy attrs are never computed.
+		if !p.selected(attr.Key) || !isToken(attr.Key) {
+			continue
+		}
+		member, ok := encodeMember(attr)
+		if !ok {
+			continue
+		}
+		if b.Len() > 0 {
+			if b.Len()+1+len(member) > p.maxBytes() {
+				break
+			}
+			b.WriteByte(',')
+		} else if len(member) > p.maxBytes() {
+			break
+		}
+		b.WriteString(member)
+		members++
+	}
+	return b.String()
+}
+
+// Decode returns a new context that is bound with the attrs encoded in value that are selected by p.
+// Malformed members and members beyond the size limits are ignored.
+func (p *Propagator) Decode(ctx context.Context, value string) context.Context {
+	if value == "" {
+		return ctx
+	}
+	if limit := p.maxBytes(); len(value) > limit {
+		// Keep only the members that end within the limit.
+		if value[limit] == ',' {
+			value = value[:limit]
+		} else if i := strings.LastIndexByte(value[:limit], ','); i >= 0 {
+			value = value[:i]
+		} else {
+			return ctx
+		}
+	}
+
+	var attrs []slog.Attr
+	for _, member := range strings.Split(value, ",") {
+		if len(attrs) >= p.maxMembers() {
+			break
+		}
+		attr, ok := decodeMember(strings.TrimSpace(member))
+		if !ok || !p.selected(attr.Key) {
+			continue
+		}
+		attrs = append(attrs, attr)
+	}
+	return WithAttrs(ctx, attrs...)
+}
+
+func (p *Propagator) selected(key string) bool {
+	for _, k := range p.Keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Propagator) maxBytes() int {
+	if p.MaxBytes > 0 {
+		return p.MaxBytes
+	}
+	return DefaultMaxPropagatedBytes
+}
+
+func (p *Propagator) maxMembers() int {
+	if p.MaxMembers > 0 {
+		return p.MaxMembers
+	}
+	return DefaultMaxPropagatedMembers
+}
+
+func encodeMember(attr slog.Attr) (string, bool) {
+	var value, hint string
+	v := attr.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		value = v.String()
+	case slog.KindInt64:
+		value, hint = strconv.FormatInt(v.Int64(), 10), typeHintInt
+	case slog.KindUint64:
+		value, hint = strconv.FormatUint(v.Uint64(), 10), typeHintUint
+	case slog.KindFloat64:
+		value, hint = strconv.FormatFloat(v.Float64(), 'g', -1, 64), typeHintFloat
+	case slog.KindBool:
+		value, hint = strconv.FormatBool(v.Bool()), typeHintBool
+	case slog.KindDuration:
+		value, hint = strconv.FormatInt(int64(v.Duration()), 10), typeHintDuration
+	case slog.KindTime:
+		value, hint = v.Time().Format(time.RFC3339Nano), typeHintTime
+	case slog.KindAny:
+		value = v.String()
+	default:
+		return "", false
+	}
+
+	member := attr.Key + "=" + url.PathEscape(value)
+	if hint != "" {
+		member += ";" + typeHintKey + "=" + hint
+	}
+	return member, true
+}
+
+func decodeMember(member string) (slog.Attr, bool) {
+	props := strings.Split(member, ";")
+	key, rawValue, ok := strings.Cut(props[0], "=")
+	key = strings.TrimSpace(key)
+	if !ok || !isToken(key) {
+		return slog.Attr{}, false
+	}
+	value, err := url.PathUnescape(strings.TrimSpace(rawValue))
+	if err != nil {
+		return slog.Attr{}, false
+	}
+
+	var hint string
+	for _, prop := range props[1:] {
+		if k, v, ok := strings.Cut(prop, "="); ok && strings.TrimSpace(k) == typeHintKey {
+			hint = strings.TrimSpace(v)
+		}
+	}
+
+	switch hint {
+	case "":
+		return slog.String(key, value), true
+	case typeHintInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		return slog.Int64(key, n), err == nil
+	case typeHintUint:
+		n, err := strconv.ParseUint(value, 10, 64)
+		return slog.Uint64(key, n), err == nil
+	case typeHintFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		return slog.Float64(key, f), err == nil
+	case typeHintBool:
+		b, err := strconv.ParseBool(value)
+		return slog.Bool(key, b), err == nil
+	case typeHintDuration:
+		n, err := strconv.ParseInt(value, 10, 64)
+		return slog.Duration(key, time.Duration(n)), err == nil
+	case typeHintTime:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		return slog.Time(key, t), err == nil
+	default:
+		return slog.String(key, value), true
+	}
+}
+
+// isToken reports whether key is a valid baggage key, i.e. an RFC 7230 token.
+func isToken(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
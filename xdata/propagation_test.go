@@ -0,0 +1,111 @@
+package xdata
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+)
+
+func TestPropagator_Encode(t *testing.T) {
+	ts := time.Date(2023, 7, 1, 12, 0, 0, 5, time.UTC)
+	ctx := WithAttrs(context.Background(),
+		slog.String("tenant", "acme corp;eu"),
+		slog.Int("shard", -7),
+		slog.Uint64("epoch", 3),
+		slog.Float64("ratio", 0.5),
+		slog.Bool("beta", true),
+		slog.Duration("budget", time.Second),
+		slog.Time("since", ts),
+		slog.Group("g", slog.String("a", "b")),
+		slog.String("bad key", "x"),
+	)
+
+	assert.Equal(t, "tenant=acme%20corp%3Beu,shard=-7;t=i,epoch=3;t=u,ratio=0.5;t=f,beta=true;t=b,"+
+		"budget=1000000000;t=d,since=2023-07-01T12:00:00.000000005Z;t=ts",
+		NewPropagator("tenant", "shard", "epoch", "ratio", "beta", "budget", "since", "g", "bad key").Encode(ctx))
+	assert.Empty(t, NewPropagator().Encode(ctx), "no keys propagate nothing")
+	assert.Equal(t, "shard=-7;t=i,tenant=acme%20corp%3Beu", NewPropagator("shard", "tenant").Encode(
+		WithAttrs(context.Background(), slog.Int("shard", -7), slog.String("tenant", "acme corp;eu"), slog.String("user", "bob")),
+	))
+	assert.Empty(t, NewPropagator("tenant").Encode(context.Background()))
+	assert.Empty(t, NewPropagator("tenant").Encode(nil))
+}
+
+func TestPropagator_RoundTrip(t *testing.T) {
+	ts := time.Date(2023, 7, 1, 12, 0, 0, 5, time.UTC)
+	attrs := []slog.Attr{
+		slog.String("tenant", "acme corp;eu,=%"),
+		slog.Int64("shard", -7),
+		slog.Uint64("epoch", 3),
+		slog.Float64("ratio", 0.5),
+		slog.Bool("beta", true),
+		slog.Duration("budget", time.Second),
+		slog.Time("since", ts),
+	}
+	p := NewPropagator("tenant", "shard", "epoch", "ratio", "beta", "budget", "since")
+	value := p.Encode(WithAttrs(context.Background(), attrs...))
+
+	assert.Equal(t, attrs, ContextAttrs(p.Decode(context.Background(), value)))
+}
+
+func TestPropagator_Decode(t *testing.T) {
+	t.Run("allowlist", func(t *testing.T) {
+		ctx := NewPropagator("tenant").Decode(context.Background(), "tenant=acme, user=bob")
+		assert.Equal(t, []slog.Attr{slog.String("tenant", "acme")}, ContextAttrs(ctx))
+		assert.Nil(t, ContextAttrs(NewPropagator().Decode(context.Background(), "tenant=acme")))
+	})
+
+	t.Run("malformed members", func(t *testing.T) {
+		ctx := NewPropagator("a", "b", "c", "d", "e", "bad key").Decode(context.Background(), "a=1;t=i,b=x;t=i,novalue,c=%zz,bad key=1,d=2;p=q,e=3;t=unknown")
+		assert.Equal(t, []slog.Attr{
+			slog.Int64("a", 1),
+			slog.String("d", "2"),
+			slog.String("e", "3"),
+		}, ContextAttrs(ctx))
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		ctx := context.Background()
+		assert.Equal(t, ctx, NewPropagator("tenant").Decode(ctx, ""))
+	})
+}
+
+func TestPropagator_Limits(t *testing.T) {
+	keys := []string{"a", "b", "c"}
+	ctx := WithAttrs(context.Background(),
+		slog.String("a", "1"), slog.String("b", "2"), slog.String("c", "3"),
+	)
+
+	assert.Equal(t, "a=1,b=2", (&Propagator{Keys: keys, MaxMembers: 2}).Encode(ctx))
+	assert.Equal(t, "a=1,b=2", (&Propagator{Keys: keys, MaxBytes: 9}).Encode(ctx))
+	assert.Empty(t, (&Propagator{Keys: keys, MaxBytes: 2}).Encode(ctx))
+
+	decoded := (&Propagator{Keys: keys, MaxMembers: 2}).Decode(context.Background(), "a=1,b=2,c=3")
+	assert.Equal(t, []slog.Attr{slog.String("a", "1"), slog.String("b", "2")}, ContextAttrs(decoded))
+	decoded = (&Propagator{Keys: keys, MaxBytes: 9}).Decode(context.Background(), "a=1,b=2,c=3")
+	assert.Equal(t, []slog.Attr{slog.String("a", "1"), slog.String("b", "2")}, ContextAttrs(decoded))
+	decoded = (&Propagator{Keys: keys, MaxBytes: 7}).Decode(context.Background(), "a=1,b=2,c=3")
+	assert.Equal(t, []slog.Attr{slog.String("a", "1"), slog.String("b", "2")}, ContextAttrs(decoded))
+	assert.Nil(t, ContextAttrs((&Propagator{Keys: keys, MaxBytes: 2}).Decode(context.Background(), "a=1")))
+
+	long := WithAttrs(context.Background(), slog.String("a", strings.Repeat("x", DefaultMaxPropagatedBytes)))
+	assert.Empty(t, NewPropagator("a").Encode(long))
+}
+
+func TestPropagator_LazyAttrs(t *testing.T) {
+	var calls int
+	ctx := WithLazyAttrs(context.Background(), "plan", func() slog.Value {
+		calls++
+		return slog.StringValue("pro")
+	})
+	ctx = WithAttrs(ctx, slog.String("tenant", "acme"))
+
+	assert.Equal(t, "tenant=acme", NewPropagator("tenant").Encode(ctx))
+	assert.Zero(t, calls, "unselected lazy attrs are not computed")
+	assert.Equal(t, "plan=pro,tenant=acme", NewPropagator("tenant", "plan").Encode(ctx))
+	assert.Equal(t, 1, calls)
+}
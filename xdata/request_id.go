@@ -0,0 +1,43 @@
+package xdata
+
+import (
+	"context"
+
+	"golang.org/x/exp/slog"
+)
+
+// RequestIDKey is the attr key under which the request ID is bound to a context.
+const RequestIDKey = "request_id"
+
+// WithRequestID returns a new context that carries id as the request ID and
+// binds it as a RequestIDKey attr. Empty ids are ignored.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if ctx == nil || id == "" {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, ctxRequestIDKey, id)
+	return WithAttrs(ctx, slog.String(RequestIDKey, id))
+}
+
+// RequestID returns the request ID bound with ctx. If no request ID is bound, it returns "".
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(ctxRequestIDKey).(string)
+	return id
+}
+
+// NewRequestID returns a new request ID from the IDSource bound with ctx.
+func NewRequestID(ctx context.Context) string {
+	return ContextIDSource(ctx).NewID()
+}
+
+// EnsureRequestID returns ctx if it carries a request ID, and otherwise a new context
+// that carries a new request ID from the IDSource bound with ctx.
+func EnsureRequestID(ctx context.Context) context.Context {
+	if ctx == nil || RequestID(ctx) != "" {
+		return ctx
+	}
+	return WithRequestID(ctx, NewRequestID(ctx))
+}
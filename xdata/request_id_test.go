@@ -0,0 +1,36 @@
+package xdata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/galecore/xslog/xtesting"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+)
+
+func TestWithRequestID(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "abc")
+	assert.Equal(t, "abc", RequestID(ctx))
+	assert.Equal(t, []slog.Attr{slog.String(RequestIDKey, "abc")}, ContextAttrs(ctx))
+
+	ctx = WithRequestID(context.Background(), "")
+	assert.Equal(t, "", RequestID(ctx))
+	assert.Nil(t, ContextAttrs(ctx))
+}
+
+func TestRequestID(t *testing.T) {
+	assert.Equal(t, "", RequestID(nil))
+	assert.Equal(t, "", RequestID(context.Background()))
+}
+
+func TestEnsureRequestID(t *testing.T) {
+	ctx := WithIDSource(context.Background(), xtesting.NewSequentialIDs("req"))
+	ctx = EnsureRequestID(ctx)
+	assert.Equal(t, "req-1", RequestID(ctx))
+	assert.Equal(t, "req-1", RequestID(EnsureRequestID(ctx)))
+	assert.Equal(t, "req-2", NewRequestID(ctx))
+
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, RequestID(EnsureRequestID(context.Background())))
+	assert.Nil(t, EnsureRequestID(nil))
+}
@@ -0,0 +1,124 @@
+package xgrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/galecore/xslog/xdata"
+	"golang.org/x/exp/slog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor returns an interceptor that propagates the request ID and context attrs
+// and logs every outgoing call.
+func UnaryClientInterceptor(opts *Options) grpc.UnaryClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		ctx = o.outgoingContext(o.withLogger(ctx))
+		start := xdata.Now(ctx)
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+		o.logCall(ctx, "grpc client call", start, err, slog.String("grpc.method", method))
+		return err
+	}
+}
+
+// StreamClientInterceptor returns an interceptor that propagates the request ID and context attrs
+// and logs every outgoing stream with its message counts once the stream is finished: when it fails,
+// when the response of a client-streaming call is received, when the server closes a server-streaming call,
+// or when ctx is done, e.g. because the caller abandoned the stream and canceled ctx.
+func StreamClientInterceptor(opts *Options) grpc.StreamClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx = o.outgoingContext(o.withLogger(ctx))
+		start := xdata.Now(ctx)
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			o.logCall(ctx, "grpc client stream", start, err, slog.String("grpc.method", method))
+			return nil, err
+		}
+		stream := &clientStream{ClientStream: cs, opts: o, ctx: ctx, desc: desc, method: method, start: start, done: make(chan struct{})}
+		if ctx.Done() != nil {
+			go stream.finishOnDone()
+		}
+		return stream, nil
+	}
+}
+
+func (o Options) outgoingContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if id := xdata.RequestID(ctx); id != "" && len(md.Get(RequestIDMetadataKey)) == 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, RequestIDMetadataKey, id)
+	}
+	if o.Propagator != nil && len(md.Get(xdata.PropagationKey)) == 0 {
+		if value := o.Propagator.Encode(ctx); value != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, xdata.PropagationKey, value)
+		}
+	}
+	return ctx
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	opts     Options
+	ctx      context.Context
+	desc     *grpc.StreamDesc
+	method   string
+	start    time.Time
+	sent     atomic.Int64
+	received atomic.Int64
+	once     sync.Once
+	done     chan struct{} // closed by finish
+}
+
+func (s *clientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.sent.Add(1)
+	} else if !errors.Is(err, io.EOF) {
+		s.finish(err)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil:
+		s.received.Add(1)
+		if !s.desc.ServerStreams {
+			// The single response of a client-streaming call ends the stream without an EOF.
+			s.finish(nil)
+		}
+	case errors.Is(err, io.EOF):
+		s.finish(nil)
+	default:
+		s.finish(err)
+	}
+	return err
+}
+
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() {
+		s.opts.logCall(s.ctx, "grpc client stream", s.start, err,
+			slog.String("grpc.method", s.method),
+			slog.Int64("grpc.sent", s.sent.Load()),
+			slog.Int64("grpc.received", s.received.Load()),
+		)
+		close(s.done)
+	})
+}
+
+// finishOnDone finishes the stream when its context is done before the stream finished otherwise.
+func (s *clientStream) finishOnDone() {
+	select {
+	case <-s.ctx.Done():
+		s.finish(status.FromContextError(s.ctx.Err()).Err())
+	case <-s.done:
+	}
+}
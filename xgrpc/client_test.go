@@ -0,0 +1,128 @@
+package xgrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/galecore/xslog"
+	"github.com/galecore/xslog/xdata"
+	"github.com/galecore/xslog/xtesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	r := xtesting.NewRecorder()
+	server := &testServer{}
+	conn := dialTestServer(t, server, &Options{Logger: newTestLogger(xtesting.NewRecorder())},
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(nil)),
+	)
+
+	ctx := xslog.WithLogger(context.Background(), newTestLogger(r))
+	ctx = xdata.WithRequestID(ctx, "abc")
+	_, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "abc", xdata.RequestID(server.ctx))
+	xtesting.RequireCount(t, r, 1)
+	xtesting.RequireLogged(t, r,
+		xtesting.WithLevel(slog.LevelInfo),
+		xtesting.MessageMatches("^grpc client call$"),
+		xtesting.AttrEqual("grpc.method", "/grpc.health.v1.Health/Check"),
+		xtesting.AttrEqual("grpc.code", "OK"),
+		xtesting.AttrEqual("request_id", "abc"),
+	)
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	r := xtesting.NewRecorder()
+	conn := dialTestServer(t, &testServer{}, &Options{Logger: newTestLogger(xtesting.NewRecorder())},
+		grpc.WithStreamInterceptor(StreamClientInterceptor(nil)),
+	)
+
+	ctx := xslog.WithLogger(context.Background(), newTestLogger(r))
+	stream, err := conn.NewStream(ctx, &testServiceDesc.Streams[0], "/xgrpc.test.Test/Count")
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg(&healthpb.HealthCheckRequest{}))
+	require.NoError(t, stream.CloseSend())
+	for {
+		if err := stream.RecvMsg(&healthpb.HealthCheckResponse{}); err != nil {
+			break
+		}
+	}
+
+	xtesting.RequireCount(t, r, 1)
+	xtesting.RequireLogged(t, r,
+		xtesting.MessageMatches("^grpc client stream$"),
+		xtesting.AttrEqual("grpc.method", "/xgrpc.test.Test/Count"),
+		xtesting.AttrEqual("grpc.sent", int64(1)),
+		xtesting.AttrEqual("grpc.received", int64(3)),
+		xtesting.AttrEqual("grpc.code", "OK"),
+	)
+}
+
+func TestStreamClientInterceptor_ClientStreaming(t *testing.T) {
+	r := xtesting.NewRecorder()
+	conn := dialTestServer(t, &testServer{}, &Options{Logger: newTestLogger(xtesting.NewRecorder())},
+		grpc.WithStreamInterceptor(StreamClientInterceptor(nil)),
+	)
+
+	ctx := xslog.WithLogger(context.Background(), newTestLogger(r))
+	stream, err := conn.NewStream(ctx, &testServiceDesc.Streams[1], "/xgrpc.test.Test/Collect")
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg(&healthpb.HealthCheckRequest{}))
+	require.NoError(t, stream.SendMsg(&healthpb.HealthCheckRequest{}))
+	require.NoError(t, stream.CloseSend())
+	require.NoError(t, stream.RecvMsg(&healthpb.HealthCheckResponse{}))
+
+	xtesting.RequireCount(t, r, 1)
+	xtesting.RequireLogged(t, r,
+		xtesting.MessageMatches("^grpc client stream$"),
+		xtesting.AttrEqual("grpc.method", "/xgrpc.test.Test/Collect"),
+		xtesting.AttrEqual("grpc.sent", int64(2)),
+		xtesting.AttrEqual("grpc.received", int64(1)),
+		xtesting.AttrEqual("grpc.code", "OK"),
+	)
+}
+
+func TestStreamClientInterceptor_Abandoned(t *testing.T) {
+	r := xtesting.NewRecorder()
+	conn := dialTestServer(t, &testServer{}, &Options{Logger: newTestLogger(xtesting.NewRecorder())},
+		grpc.WithStreamInterceptor(StreamClientInterceptor(nil)),
+	)
+
+	ctx, cancel := context.WithCancel(xslog.WithLogger(context.Background(), newTestLogger(r)))
+	_, err := conn.NewStream(ctx, &testServiceDesc.Streams[0], "/xgrpc.test.Test/Count")
+	require.NoError(t, err)
+	cancel()
+
+	assert.Eventually(t, func() bool {
+		return len(r.Records()) > 0
+	}, time.Second, time.Millisecond)
+	xtesting.RequireCount(t, r, 1)
+	xtesting.RequireLogged(t, r,
+		xtesting.MessageMatches("^grpc client stream$"),
+		xtesting.AttrEqual("grpc.sent", int64(0)),
+		xtesting.AttrEqual("grpc.received", int64(0)),
+		xtesting.AttrEqual("grpc.code", "Canceled"),
+	)
+}
+
+func TestUnaryClientInterceptor_Propagator(t *testing.T) {
+	propagator := xdata.NewPropagator("tenant", "shard")
+	server := &testServer{}
+	conn := dialTestServer(t, server, &Options{Logger: newTestLogger(xtesting.NewRecorder()), Propagator: propagator},
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(&Options{Propagator: propagator})),
+	)
+
+	ctx := xslog.WithLogger(context.Background(), newTestLogger(xtesting.NewRecorder()))
+	ctx = xdata.WithAttrs(ctx, slog.String("tenant", "acme"), slog.Int("shard", 7), slog.String("user", "bob"))
+	_, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []slog.Attr{slog.String("tenant", "acme"), slog.Int64("shard", 7)}, xdata.ContextAttrs(server.ctx)[2:])
+}
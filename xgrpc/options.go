@@ -0,0 +1,73 @@
+package xgrpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/galecore/xslog"
+	"github.com/galecore/xslog/xdata"
+	"golang.org/x/exp/slog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDMetadataKey is the metadata key used to propagate the request ID between services.
+const RequestIDMetadataKey = "x-request-id"
+
+// CodeToLevel chooses the level of a call record from the call status code.
+type CodeToLevel func(code codes.Code) slog.Level
+
+// DefaultCodeToLevel logs client mistakes at Info, transient failures at Warn and server faults at Error.
+func DefaultCodeToLevel(code codes.Code) slog.Level {
+	switch code {
+	case codes.OK, codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.Unauthenticated:
+		return slog.LevelInfo
+	case codes.DeadlineExceeded, codes.PermissionDenied, codes.ResourceExhausted, codes.FailedPrecondition,
+		codes.Aborted, codes.OutOfRange, codes.Unavailable:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+// Options configures the interceptors.
+type Options struct {
+	// Logger is bound to the context of calls that have no logger yet.
+	// If nil, such calls are logged through xslog.Default.
+	Logger *slog.Logger
+	// CodeToLevel chooses the level of call records. Defaults to DefaultCodeToLevel.
+	CodeToLevel CodeToLevel
+	// Propagator, if set, restores context attrs from the xdata.PropagationKey metadata entry of
+	// incoming calls and encodes them into the metadata of outgoing calls.
+	Propagator *xdata.Propagator
+}
+
+func newOptions(opts *Options) Options {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.CodeToLevel == nil {
+		o.CodeToLevel = DefaultCodeToLevel
+	}
+	return o
+}
+
+func (o Options) logCall(ctx context.Context, msg string, start time.Time, err error, attrs ...slog.Attr) {
+	code := status.Code(err)
+	attrs = append(attrs,
+		slog.String("grpc.code", code.String()),
+		slog.Duration("duration", xdata.Since(ctx, start)),
+	)
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	xslog.Log(ctx, o.CodeToLevel(code), msg, attrs)
+}
+
+func (o Options) withLogger(ctx context.Context) context.Context {
+	if o.Logger == nil || xslog.ContextLogger(ctx) != nil {
+		return ctx
+	}
+	return xslog.WithLogger(ctx, o.Logger)
+}
@@ -0,0 +1,106 @@
+package xgrpc
+
+import (
+	"context"
+	"runtime/debug"
+	"sync/atomic"
+
+	"github.com/galecore/xslog"
+	"github.com/galecore/xslog/xdata"
+	"golang.org/x/exp/slog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns an interceptor that binds a logger and call attrs to the handler
+// context, logs every call and recovers handler panics into codes.Internal errors.
+func UnaryServerInterceptor(opts *Options) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		ctx = o.serverContext(ctx, info.FullMethod)
+		start := xdata.Now(ctx)
+		defer func() {
+			if r := recover(); r != nil {
+				err = o.recovered(ctx, r)
+			}
+			o.logCall(ctx, "grpc server call", start, err)
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that binds a logger and call attrs to the stream
+// context, logs every stream with its message counts and recovers handler panics into codes.Internal errors.
+func StreamServerInterceptor(opts *Options) grpc.StreamServerInterceptor {
+	o := newOptions(opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		stream := &serverStream{ServerStream: ss, ctx: o.serverContext(ss.Context(), info.FullMethod)}
+		start := xdata.Now(stream.ctx)
+		defer func() {
+			if r := recover(); r != nil {
+				err = o.recovered(stream.ctx, r)
+			}
+			o.logCall(stream.ctx, "grpc server stream", start, err,
+				slog.Int64("grpc.sent", stream.sent.Load()),
+				slog.Int64("grpc.received", stream.received.Load()),
+			)
+		}()
+		return handler(srv, stream)
+	}
+}
+
+func (o Options) serverContext(ctx context.Context, method string) context.Context {
+	ctx = o.withLogger(ctx)
+	attrs := []slog.Attr{slog.String("grpc.method", method)}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		attrs = append(attrs, slog.String("peer", p.Addr.String()))
+	}
+	ctx = xdata.WithAttrs(ctx, attrs...)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(RequestIDMetadataKey); len(ids) > 0 {
+			ctx = xdata.WithRequestID(ctx, ids[0])
+		}
+		if values := md.Get(xdata.PropagationKey); len(values) > 0 && o.Propagator != nil {
+			ctx = o.Propagator.Decode(ctx, values[0])
+		}
+	}
+	return ctx
+}
+
+func (o Options) recovered(ctx context.Context, r any) error {
+	xslog.Log(ctx, slog.LevelError, "grpc handler panicked", []slog.Attr{
+		slog.Any("panic", r),
+		slog.String("stack", string(debug.Stack())),
+	})
+	return status.Errorf(codes.Internal, "panic: %v", r)
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	sent     atomic.Int64
+	received atomic.Int64
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent.Add(1)
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received.Add(1)
+	}
+	return err
+}
@@ -0,0 +1,206 @@
+package xgrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/galecore/xslog/xdata"
+	"github.com/galecore/xslog/xtesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestLogger(h slog.Handler) *slog.Logger {
+	return slog.New(xdata.NewHandler(h))
+}
+
+var testServiceDesc = grpc.ServiceDesc{
+	ServiceName: "xgrpc.test.Test",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Panic",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := new(healthpb.HealthCheckRequest)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/xgrpc.test.Test/Panic"}
+				return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+					panic("boom")
+				})
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Count",
+			ServerStreams: true,
+			Handler: func(srv any, stream grpc.ServerStream) error {
+				in := new(healthpb.HealthCheckRequest)
+				if err := stream.RecvMsg(in); err != nil {
+					return err
+				}
+				for i := 0; i < 3; i++ {
+					if err := stream.SendMsg(&healthpb.HealthCheckResponse{}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			StreamName:    "Collect",
+			ClientStreams: true,
+			Handler: func(srv any, stream grpc.ServerStream) error {
+				for {
+					err := stream.RecvMsg(new(healthpb.HealthCheckRequest))
+					if errors.Is(err, io.EOF) {
+						return stream.SendMsg(&healthpb.HealthCheckResponse{})
+					}
+					if err != nil {
+						return err
+					}
+				}
+			},
+		},
+	},
+}
+
+type testServer struct {
+	healthpb.UnimplementedHealthServer
+	ctx context.Context
+}
+
+func (s *testServer) Check(ctx context.Context, _ *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	s.ctx = ctx
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func dialTestServer(t *testing.T, server *testServer, serverOpts *Options, dialOpts ...grpc.DialOption) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(serverOpts)),
+		grpc.StreamInterceptor(StreamServerInterceptor(serverOpts)),
+	)
+	healthpb.RegisterHealthServer(srv, server)
+	srv.RegisterService(&testServiceDesc, server)
+	go func() { _ = srv.Serve(listener) }()
+	t.Cleanup(srv.Stop)
+
+	dialOpts = append(dialOpts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.Dial("bufnet", dialOpts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		r := xtesting.NewRecorder()
+		server := &testServer{}
+		conn := dialTestServer(t, server, &Options{Logger: newTestLogger(r)})
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(), RequestIDMetadataKey, "abc")
+		_, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+
+		assert.Equal(t, "abc", xdata.RequestID(server.ctx))
+		xtesting.RequireCount(t, r, 1)
+		xtesting.RequireLogged(t, r,
+			xtesting.WithLevel(slog.LevelInfo),
+			xtesting.MessageMatches("^grpc server call$"),
+			xtesting.AttrEqual("grpc.code", "OK"),
+			xtesting.HasAttr("duration"),
+			xtesting.AttrEqual("grpc.method", "/grpc.health.v1.Health/Check"),
+			xtesting.AttrEqual("peer", "bufconn"),
+			xtesting.AttrEqual("request_id", "abc"),
+		)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		r := xtesting.NewRecorder()
+		conn := dialTestServer(t, &testServer{}, &Options{Logger: newTestLogger(r)})
+
+		err := conn.Invoke(context.Background(), "/xgrpc.test.Test/Panic", &healthpb.HealthCheckRequest{}, &healthpb.HealthCheckResponse{})
+		assert.Equal(t, codes.Internal, status.Code(err))
+
+		records := r.Records()
+		require.Len(t, records, 2)
+		assert.Equal(t, "grpc handler panicked", records[0].Message)
+		xtesting.RequireLogged(t, r,
+			xtesting.WithLevel(slog.LevelError),
+			xtesting.MessageMatches("^grpc handler panicked$"),
+			xtesting.AttrEqual("panic", "boom"),
+			xtesting.HasAttr("stack"),
+		)
+		xtesting.RequireLogged(t, r,
+			xtesting.WithLevel(slog.LevelError),
+			xtesting.MessageMatches("^grpc server call$"),
+			xtesting.AttrEqual("grpc.code", "Internal"),
+			xtesting.AttrEqual("error", "rpc error: code = Internal desc = panic: boom"),
+		)
+	})
+
+	t.Run("code to level", func(t *testing.T) {
+		r := xtesting.NewRecorder()
+		conn := dialTestServer(t, &testServer{}, &Options{
+			Logger: newTestLogger(r),
+			CodeToLevel: func(codes.Code) slog.Level {
+				return slog.LevelDebug
+			},
+		})
+
+		_, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+		xtesting.RequireLogged(t, r, xtesting.WithLevel(slog.LevelDebug), xtesting.MessageMatches("^grpc server call$"))
+	})
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	r := xtesting.NewRecorder()
+	conn := dialTestServer(t, &testServer{}, &Options{Logger: newTestLogger(r)})
+
+	stream, err := conn.NewStream(context.Background(), &testServiceDesc.Streams[0], "/xgrpc.test.Test/Count")
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg(&healthpb.HealthCheckRequest{}))
+	require.NoError(t, stream.CloseSend())
+	for {
+		if err := stream.RecvMsg(&healthpb.HealthCheckResponse{}); err != nil {
+			break
+		}
+	}
+
+	xtesting.RequireLogged(t, r,
+		xtesting.WithLevel(slog.LevelInfo),
+		xtesting.MessageMatches("^grpc server stream$"),
+		xtesting.AttrEqual("grpc.sent", int64(3)),
+		xtesting.AttrEqual("grpc.received", int64(1)),
+		xtesting.AttrEqual("grpc.code", "OK"),
+		xtesting.AttrEqual("grpc.method", "/xgrpc.test.Test/Count"),
+	)
+}
+
+func TestDefaultCodeToLevel(t *testing.T) {
+	assert.Equal(t, slog.LevelInfo, DefaultCodeToLevel(codes.OK))
+	assert.Equal(t, slog.LevelInfo, DefaultCodeToLevel(codes.NotFound))
+	assert.Equal(t, slog.LevelWarn, DefaultCodeToLevel(codes.Unavailable))
+	assert.Equal(t, slog.LevelError, DefaultCodeToLevel(codes.Internal))
+	assert.Equal(t, slog.LevelError, DefaultCodeToLevel(codes.Unknown))
+}
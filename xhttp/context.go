@@ -0,0 +1,47 @@
+package xhttp
+
+import (
+	"context"
+)
+
+type ctxKey int
+
+const (
+	ctxPathTemplateKey ctxKey = iota
+	ctxRetryCountKey
+)
+
+// WithPathTemplate returns a new context that is bound with a route template (e.g. "/users/{id}")
+// which is logged instead of the raw request path.
+func WithPathTemplate(ctx context.Context, template string) context.Context {
+	if ctx == nil || template == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxPathTemplateKey, template)
+}
+
+// ContextPathTemplate returns the route template bound with ctx. If no template is bound, it returns "".
+func ContextPathTemplate(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	template, _ := ctx.Value(ctxPathTemplateKey).(string)
+	return template
+}
+
+// WithRetryCount returns a new context that is bound with the number of previous attempts of a request.
+func WithRetryCount(ctx context.Context, retry int) context.Context {
+	if ctx == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxRetryCountKey, retry)
+}
+
+// ContextRetryCount returns the retry count bound with ctx. If no count is bound, it returns 0.
+func ContextRetryCount(ctx context.Context) int {
+	if ctx == nil {
+		return 0
+	}
+	retry, _ := ctx.Value(ctxRetryCountKey).(int)
+	return retry
+}
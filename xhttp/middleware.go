@@ -0,0 +1,151 @@
+package xhttp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/galecore/xslog"
+	"golang.org/x/exp/slog"
+)
+
+// MiddlewareOptions configures CanonicalLogMiddleware.
+type MiddlewareOptions struct {
+	// Level is used for requests that completed with a status below 500. Defaults to slog.LevelInfo.
+	Level slog.Leveler
+	// ErrorLevel is used for 5xx responses and panics. Defaults to slog.LevelError.
+	ErrorLevel slog.Leveler
+}
+
+// CanonicalLogMiddleware returns a middleware that starts a canonical log line event for every request
+// and emits it with xslog.EmitEvent once the request completes, even if the handler panics.
+// Handlers add to the event with xslog.AddToEvent, xslog.CountInEvent and xslog.TimeInEvent.
+// Panics are recorded on the event and raised again after it was emitted.
+func CanonicalLogMiddleware(opts *MiddlewareOptions) func(http.Handler) http.Handler {
+	var o MiddlewareOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Level == nil {
+		o.Level = slog.LevelInfo
+	}
+	if o.ErrorLevel == nil {
+		o.ErrorLevel = slog.LevelError
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := xslog.StartEvent(r.Context())
+			rw := &responseWriter{ResponseWriter: w}
+			path := ContextPathTemplate(ctx)
+			if path == "" {
+				path = r.URL.Path
+			}
+			// Status and bytes are set up front to keep their position; handlers may replace path with a route template.
+			xslog.AddToEvent(ctx,
+				slog.String("method", r.Method),
+				slog.String("path", path),
+				slog.Int("status", 0),
+				slog.Int64("bytes", 0),
+			)
+
+			defer func() {
+				p := recover()
+				level := o.Level
+				if p != nil {
+					xslog.AddToEvent(ctx, slog.String("panic", fmt.Sprint(p)))
+					if rw.status == 0 {
+						rw.status = http.StatusInternalServerError
+					}
+				}
+				if p != nil || rw.status >= http.StatusInternalServerError {
+					level = o.ErrorLevel
+				}
+				xslog.AddToEvent(ctx, slog.Int("status", rw.statusCode()), slog.Int64("bytes", rw.bytes))
+				xslog.EmitEvent(ctx, level, "http request")
+				if p != nil {
+					panic(p)
+				}
+			}()
+			next.ServeHTTP(rw.wrap(), r.WithContext(ctx))
+		})
+	}
+}
+
+// responseWriter records the status and size of a response.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// wrap returns w as an http.ResponseWriter that implements http.Flusher and http.Hijacker
+// if the underlying writer does, so that handlers can still stream and upgrade connections.
+func (w *responseWriter) wrap() http.ResponseWriter {
+	_, flusher := w.ResponseWriter.(http.Flusher)
+	_, hijacker := w.ResponseWriter.(http.Hijacker)
+	switch {
+	case flusher && hijacker:
+		return flushHijackWriter{w}
+	case flusher:
+		return flushWriter{w}
+	case hijacker:
+		return hijackWriter{w}
+	default:
+		return w
+	}
+}
+
+type flushWriter struct{ *responseWriter }
+
+func (w flushWriter) Flush() { w.flush() }
+
+type hijackWriter struct{ *responseWriter }
+
+func (w hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+type flushHijackWriter struct{ *responseWriter }
+
+func (w flushHijackWriter) Flush() { w.flush() }
+
+func (w flushHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+func (w *responseWriter) flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *responseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the optional interfaces of the underlying writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
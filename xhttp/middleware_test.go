@@ -0,0 +1,112 @@
+package xhttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/galecore/xslog"
+	"github.com/galecore/xslog/xtesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+)
+
+func TestCanonicalLogMiddleware(t *testing.T) {
+	t.Run("emits event", func(t *testing.T) {
+		ctx, rec := newTestContext()
+		handler := CanonicalLogMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			xslog.AddToEvent(r.Context(), slog.String("path", "/users/{id}"), slog.String("user", "bob"))
+			xslog.CountInEvent(r.Context(), "cache_hits", 2)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("hello"))
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/users/42", nil).WithContext(ctx)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		records := rec.Records()
+		require.Len(t, records, 1)
+		assert.Equal(t, "INFO: http request [method=POST path=/users/{id} status=201 bytes=5 user=bob cache_hits=2 duration=0s]", records[0].String())
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		ctx, rec := newTestContext()
+		handler := CanonicalLogMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+		xtesting.RequireLogged(t, rec, xtesting.WithLevel(slog.LevelError), xtesting.AttrEqual("status", http.StatusServiceUnavailable))
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		ctx, rec := newTestContext()
+		handler := CanonicalLogMiddleware(&MiddlewareOptions{ErrorLevel: slog.LevelWarn})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			xslog.AddToEvent(r.Context(), slog.String("user", "bob"))
+			panic("boom")
+		}))
+
+		assert.PanicsWithValue(t, "boom", func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+		})
+		xtesting.RequireLogged(t, rec,
+			xtesting.WithLevel(slog.LevelWarn),
+			xtesting.AttrEqual("status", http.StatusInternalServerError),
+			xtesting.AttrEqual("user", "bob"),
+			xtesting.AttrEqual("panic", "boom"),
+		)
+	})
+
+	t.Run("forwards flusher and hijacker", func(t *testing.T) {
+		ctx, rec := newTestContext()
+		var flushed, hijackable bool
+		handler := CanonicalLogMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, hijackable = w.(http.Hijacker)
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+				flushed = true
+			}
+		}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+		assert.True(t, flushed)
+		assert.True(t, w.Flushed)
+		assert.False(t, hijackable, "httptest.ResponseRecorder cannot be hijacked")
+		xtesting.RequireLogged(t, rec, xtesting.WithLevel(slog.LevelInfo), xtesting.AttrEqual("status", http.StatusOK))
+	})
+
+	t.Run("hijack", func(t *testing.T) {
+		ctx, _ := newTestContext()
+		handler := CanonicalLogMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer conn.Close()
+			_, _ = buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")
+			_ = buf.Flush()
+		}))
+		srv := httptest.NewServer(injectContext(ctx, handler))
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", string(body))
+	})
+}
+
+// injectContext serves requests with the values of ctx, like a server with a BaseContext.
+func injectContext(ctx context.Context, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
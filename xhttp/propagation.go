@@ -0,0 +1,46 @@
+package xhttp
+
+import (
+	"net/http"
+
+	"github.com/galecore/xslog/xdata"
+)
+
+// PropagationMiddleware returns a middleware that binds the attrs propagated in the xdata.PropagationKey
+// header to the request context, so that they are logged by the handler and propagated further by
+// NewPropagationTransport.
+func PropagationMiddleware(p *xdata.Propagator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if value := r.Header.Get(xdata.PropagationKey); value != "" {
+				r = r.WithContext(p.Decode(r.Context(), value))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// NewPropagationTransport returns an http.RoundTripper that sets the xdata.PropagationKey header of
+// outbound requests to the attrs of the request context selected by p. Requests that already carry
+// the header are sent unchanged. If next is nil, http.DefaultTransport is used.
+func NewPropagationTransport(next http.RoundTripper, p *xdata.Propagator) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &propagationTransport{next: next, p: p}
+}
+
+type propagationTransport struct {
+	next http.RoundTripper
+	p    *xdata.Propagator
+}
+
+func (t *propagationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get(xdata.PropagationKey) == "" {
+		if value := t.p.Encode(req.Context()); value != "" {
+			req = req.Clone(req.Context())
+			req.Header.Set(xdata.PropagationKey, value)
+		}
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,71 @@
+package xhttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/galecore/xslog"
+	"github.com/galecore/xslog/xdata"
+	"github.com/galecore/xslog/xtesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+)
+
+func TestPropagation(t *testing.T) {
+	propagator := xdata.NewPropagator("tenant")
+	client := &http.Client{Transport: NewPropagationTransport(nil, propagator)}
+	call := func(ctx context.Context, url string) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+
+	ctx, rec := newTestContext()
+	hop := func(next string) http.Handler {
+		return PropagationMiddleware(propagator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := xslog.TransferLogger(r.Context(), ctx)
+			xslog.Info(ctx, "handled", slog.String("path", r.URL.Path))
+			if next != "" {
+				call(ctx, next)
+			}
+		}))
+	}
+	third := httptest.NewServer(hop(""))
+	defer third.Close()
+	second := httptest.NewServer(hop(third.URL + "/third"))
+	defer second.Close()
+	first := httptest.NewServer(hop(second.URL + "/second"))
+	defer first.Close()
+
+	gateway := xdata.WithAttrs(ctx, slog.String("tenant", "acme"), slog.String("user", "bob"))
+	call(gateway, first.URL+"/first")
+
+	for _, path := range []string{"/first", "/second", "/third"} {
+		xtesting.RequireLogged(t, rec, xtesting.AttrEqual("path", path), xtesting.AttrEqual("tenant", "acme"))
+	}
+	xtesting.RequireCount(t, rec, 3)
+	xtesting.RequireNotLogged(t, rec, xtesting.HasAttr("user"))
+}
+
+func TestPropagationTransport_KeepsHeader(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(xdata.PropagationKey)
+	}))
+	defer server.Close()
+
+	ctx := xdata.WithAttrs(context.Background(), slog.String("tenant", "acme"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set(xdata.PropagationKey, "tenant=other")
+	resp, err := (&http.Client{Transport: NewPropagationTransport(nil, xdata.NewPropagator("tenant"))}).Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, "tenant=other", got)
+}
@@ -0,0 +1,249 @@
+package xhttp
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/galecore/xslog"
+	"github.com/galecore/xslog/xdata"
+	"golang.org/x/exp/slog"
+)
+
+// RequestIDHeader is the header used to propagate the request ID between services.
+const RequestIDHeader = "X-Request-Id"
+
+// RedactedValue replaces the values of redacted headers.
+const RedactedValue = "[REDACTED]"
+
+// DefaultMaxBodySize is the number of body bytes captured when TransportOptions.MaxBodySize is not set.
+const DefaultMaxBodySize = 4 << 10
+
+// DefaultRedactedHeaders are the headers whose values are never logged.
+var DefaultRedactedHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "Set-Cookie"}
+
+// TransportOptions configures a Transport.
+type TransportOptions struct {
+	// Level is used for calls that completed with a status below 500. Defaults to slog.LevelInfo.
+	Level slog.Leveler
+	// ErrorLevel is used for failed calls and 5xx responses. Defaults to slog.LevelError.
+	ErrorLevel slog.Leveler
+
+	// LogHeaders enables capturing of request and response headers.
+	LogHeaders bool
+	// RedactedHeaders lists headers whose values are replaced with RedactedValue.
+	// Defaults to DefaultRedactedHeaders.
+	RedactedHeaders []string
+
+	// LogBodies enables capturing of request and response bodies. Bodies are captured while they are read,
+	// so streaming bodies are not held back: the request body as far as the transport has sent it, and the
+	// response body as the caller reads it. The request is logged once the response body is read to the end
+	// or closed. Bodies of 101 Switching Protocols responses are not captured.
+	LogBodies bool
+	// MaxBodySize caps the number of captured body bytes. Defaults to DefaultMaxBodySize.
+	MaxBodySize int
+	// RedactBody, if set, is applied to every captured body before it is logged.
+	RedactBody func(body []byte) []byte
+}
+
+// Transport is an http.RoundTripper that logs every outbound request through the context logger.
+type Transport struct {
+	next     http.RoundTripper
+	opts     TransportOptions
+	redacted map[string]struct{}
+}
+
+// NewTransport returns a Transport wrapping next. If next is nil, http.DefaultTransport is used.
+func NewTransport(next http.RoundTripper, opts *TransportOptions) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &Transport{next: next}
+	if opts != nil {
+		t.opts = *opts
+	}
+	if t.opts.Level == nil {
+		t.opts.Level = slog.LevelInfo
+	}
+	if t.opts.ErrorLevel == nil {
+		t.opts.ErrorLevel = slog.LevelError
+	}
+	if t.opts.RedactedHeaders == nil {
+		t.opts.RedactedHeaders = DefaultRedactedHeaders
+	}
+	if t.opts.MaxBodySize <= 0 {
+		t.opts.MaxBodySize = DefaultMaxBodySize
+	}
+	t.redacted = make(map[string]struct{}, len(t.opts.RedactedHeaders))
+	for _, header := range t.opts.RedactedHeaders {
+		t.redacted[http.CanonicalHeaderKey(header)] = struct{}{}
+	}
+	return t
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	if id := xdata.RequestID(ctx); id != "" && req.Header.Get(RequestIDHeader) == "" {
+		req = req.Clone(ctx)
+		req.Header.Set(RequestIDHeader, id)
+	}
+
+	var reqBody *capturedBody
+	if t.opts.LogBodies && req.Body != nil && req.Body != http.NoBody {
+		reqBody = &capturedBody{ReadCloser: req.Body, limit: t.opts.MaxBodySize + 1}
+		req = req.Clone(ctx)
+		req.Body = reqBody
+	}
+
+	start := xdata.Now(ctx)
+	resp, err := t.next.RoundTrip(req)
+	duration := xdata.Since(ctx, start)
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("host", req.URL.Host),
+		slog.String("path", pathTemplate(req)),
+	}
+	level := t.opts.Level.Level()
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+		if resp.StatusCode >= http.StatusInternalServerError {
+			level = t.opts.ErrorLevel.Level()
+		}
+	}
+	attrs = append(attrs,
+		slog.Duration("duration", duration),
+		slog.Int("retry", ContextRetryCount(ctx)),
+	)
+	if err != nil {
+		level = t.opts.ErrorLevel.Level()
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	if t.opts.LogHeaders {
+		attrs = append(attrs, t.headersAttr("request_headers", req.Header))
+		if resp != nil {
+			attrs = append(attrs, t.headersAttr("response_headers", resp.Header))
+		}
+	}
+	if t.opts.LogBodies {
+		// The bodies are captured while the transport and the caller read them.
+		// Bodies of protocol upgrades are left alone, since they must stay writable.
+		if resp != nil && resp.Body != nil && resp.Body != http.NoBody && resp.StatusCode != http.StatusSwitchingProtocols {
+			attrs := attrs[:len(attrs):len(attrs)]
+			resp.Body = &capturedBody{ReadCloser: resp.Body, limit: t.opts.MaxBodySize + 1, done: func(body []byte) {
+				attrs = t.appendRequestBody(attrs, reqBody)
+				xslog.Log(ctx, level, "http client request", append(attrs, t.bodyAttr("response_body", body)))
+			}}
+			return resp, err
+		}
+		attrs = t.appendRequestBody(attrs, reqBody)
+	}
+
+	xslog.Log(ctx, level, "http client request", attrs)
+	return resp, err
+}
+
+// appendRequestBody appends the part of the request body captured so far to attrs, if it is captured.
+func (t *Transport) appendRequestBody(attrs []slog.Attr, body *capturedBody) []slog.Attr {
+	if body == nil {
+		return attrs
+	}
+	return append(attrs, t.bodyAttr("request_body", body.bytes()))
+}
+
+func (t *Transport) bodyAttr(key string, body []byte) slog.Attr {
+	truncated := len(body) > t.opts.MaxBodySize
+	if truncated {
+		body = body[:t.opts.MaxBodySize]
+	}
+	if t.opts.RedactBody != nil {
+		body = t.opts.RedactBody(append([]byte(nil), body...))
+	}
+	return slog.Group(key,
+		slog.String("content", string(body)),
+		slog.Bool("truncated", truncated),
+	)
+}
+
+func (t *Transport) headersAttr(key string, header http.Header) slog.Attr {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attrs := make([]slog.Attr, 0, len(names))
+	for _, name := range names {
+		value := strings.Join(header[name], ", ")
+		if _, ok := t.redacted[http.CanonicalHeaderKey(name)]; ok {
+			value = RedactedValue
+		}
+		attrs = append(attrs, slog.String(name, value))
+	}
+	return slog.Attr{Key: key, Value: slog.GroupValue(attrs...)}
+}
+
+func pathTemplate(req *http.Request) string {
+	if template := ContextPathTemplate(req.Context()); template != "" {
+		return template
+	}
+	return req.URL.Path
+}
+
+// capturedBody captures up to limit bytes of a body while it is read, and passes them
+// to done, if set, once it is read to the end or closed.
+type capturedBody struct {
+	io.ReadCloser
+	limit int
+	done  func(body []byte)
+
+	mu       sync.Mutex
+	captured []byte
+	finished bool
+}
+
+func (b *capturedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mu.Lock()
+	if room := b.limit - len(b.captured); room > 0 {
+		if room > n {
+			room = n
+		}
+		b.captured = append(b.captured, p[:room]...)
+	}
+	b.mu.Unlock()
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *capturedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish()
+	return err
+}
+
+// bytes returns the bytes captured so far. The body may still be read concurrently.
+func (b *capturedBody) bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.captured...)
+}
+
+func (b *capturedBody) finish() {
+	b.mu.Lock()
+	if b.finished {
+		b.mu.Unlock()
+		return
+	}
+	b.finished = true
+	captured := b.captured
+	b.mu.Unlock()
+	if b.done != nil {
+		b.done(captured)
+	}
+}
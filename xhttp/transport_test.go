@@ -0,0 +1,253 @@
+package xhttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/galecore/xslog"
+	"github.com/galecore/xslog/xdata"
+	"github.com/galecore/xslog/xtesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+)
+
+// newTestContext returns a context that logs to the returned recorder and whose clock never moves.
+func newTestContext() (context.Context, *xtesting.Recorder) {
+	r := xtesting.NewRecorder()
+	ctx := xslog.WithLogger(context.Background(), slog.New(xdata.NewHandler(r)))
+	return xdata.WithClock(ctx, xtesting.NewFakeClock(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), 0)), r
+}
+
+func TestTransport_RoundTrip(t *testing.T) {
+	t.Run("logs request", func(t *testing.T) {
+		var gotRequestID string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotRequestID = r.Header.Get(RequestIDHeader)
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		ctx, rec := newTestContext()
+		ctx = xdata.WithRequestID(ctx, "abc")
+		ctx = WithPathTemplate(ctx, "/users/{id}")
+		ctx = WithRetryCount(ctx, 2)
+
+		client := &http.Client{Transport: NewTransport(nil, nil)}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/users/42", nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		assert.Equal(t, "abc", gotRequestID)
+		assert.Empty(t, req.Header.Get(RequestIDHeader))
+		xtesting.RequireCount(t, rec, 1)
+		xtesting.RequireLogged(t, rec,
+			xtesting.WithLevel(slog.LevelInfo),
+			xtesting.MessageMatches("^http client request$"),
+			xtesting.AttrEqual("method", http.MethodPost),
+			xtesting.AttrEqual("host", strings.TrimPrefix(server.URL, "http://")),
+			xtesting.AttrEqual("path", "/users/{id}"),
+			xtesting.AttrEqual("status", http.StatusCreated),
+			xtesting.AttrEqual("duration", time.Duration(0)),
+			xtesting.AttrEqual("retry", 2),
+			xtesting.AttrEqual("request_id", "abc"),
+		)
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		ctx, rec := newTestContext()
+		client := &http.Client{Transport: NewTransport(nil, nil)}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/items", nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		xtesting.RequireLogged(t, rec,
+			xtesting.WithLevel(slog.LevelError),
+			xtesting.AttrEqual("path", "/items"),
+			xtesting.AttrEqual("status", http.StatusBadGateway),
+		)
+	})
+
+	t.Run("transport error", func(t *testing.T) {
+		ctx, rec := newTestContext()
+		failing := roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		})
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+		_, err = NewTransport(failing, nil).RoundTrip(req)
+		assert.Error(t, err)
+
+		xtesting.RequireLogged(t, rec,
+			xtesting.WithLevel(slog.LevelError),
+			xtesting.AttrEqual("retry", 0),
+			xtesting.AttrEqual("error", "connection refused"),
+		)
+		xtesting.RequireNotLogged(t, rec, xtesting.HasAttr("status"))
+	})
+}
+
+func TestTransport_Capture(t *testing.T) {
+	var gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.Header().Set("Set-Cookie", "session=secret")
+		_, _ = w.Write([]byte("response body"))
+	}))
+	defer server.Close()
+
+	ctx, rec := newTestContext()
+	transport := NewTransport(nil, &TransportOptions{
+		LogHeaders:  true,
+		LogBodies:   true,
+		MaxBodySize: 8,
+		RedactBody: func(body []byte) []byte {
+			return []byte(strings.ReplaceAll(string(body), "resp", "****"))
+		},
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, strings.NewReader("request body"))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("Accept", "text/plain")
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, "request body", gotBody)
+	assert.Equal(t, "response body", string(body))
+
+	xtesting.RequireLogged(t, rec,
+		xtesting.InGroup("request_headers",
+			xtesting.AttrEqual("Accept", "text/plain"),
+			xtesting.AttrEqual("Authorization", RedactedValue),
+		),
+		xtesting.AttrEqual("response_headers.Set-Cookie", RedactedValue),
+		xtesting.InGroup("request_body", xtesting.AttrEqual("content", "request "), xtesting.AttrEqual("truncated", true)),
+		xtesting.InGroup("response_body", xtesting.AttrEqual("content", "****onse"), xtesting.AttrEqual("truncated", true)),
+	)
+	assert.NotContains(t, rec.Records()[0].String(), "Bearer token")
+}
+
+func TestTransport_StreamingBody(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("first "))
+		w.(http.Flusher).Flush()
+		<-release
+		_, _ = w.Write([]byte("second"))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, rec := newTestContext()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: NewTransport(nil, &TransportOptions{LogBodies: true})}).Do(req)
+	require.NoError(t, err, "the response is returned before the stream ends")
+	defer resp.Body.Close()
+
+	first := make([]byte, len("first "))
+	_, err = io.ReadFull(resp.Body, first)
+	require.NoError(t, err)
+	assert.Equal(t, "first ", string(first))
+	assert.Empty(t, rec.Records(), "the request is logged once the body is read")
+
+	release <- struct{}{}
+	rest, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(rest))
+	require.NoError(t, resp.Body.Close())
+
+	xtesting.RequireCount(t, rec, 1)
+	xtesting.RequireLogged(t, rec,
+		xtesting.InGroup("response_body", xtesting.AttrEqual("content", "first second"), xtesting.AttrEqual("truncated", false)),
+	)
+}
+
+func TestTransport_StreamingUpload(t *testing.T) {
+	received := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		first := make([]byte, len("first "))
+		_, _ = io.ReadFull(r.Body, first)
+		close(received)
+		rest, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(append(first, rest...))
+	}))
+	defer server.Close()
+
+	body, writer := io.Pipe()
+	go func() {
+		_, _ = writer.Write([]byte("first "))
+		// The rest is only written once the server received the first part, which requires the request to be sent.
+		<-received
+		_, _ = writer.Write([]byte("second"))
+		_ = writer.Close()
+	}()
+
+	ctx, rec := newTestContext()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, body)
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: NewTransport(nil, &TransportOptions{LogBodies: true})}).Do(req)
+	require.NoError(t, err)
+	got, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	assert.Equal(t, "first second", string(got))
+	xtesting.RequireLogged(t, rec,
+		xtesting.InGroup("request_body", xtesting.AttrEqual("content", "first second"), xtesting.AttrEqual("truncated", false)),
+		xtesting.InGroup("response_body", xtesting.AttrEqual("content", "first second"), xtesting.AttrEqual("truncated", false)),
+	)
+}
+
+func TestTransport_SwitchingProtocols(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		_ = buf.Flush()
+	}))
+	defer server.Close()
+
+	ctx, rec := newTestContext()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "echo")
+	resp, err := (&http.Client{Transport: NewTransport(nil, &TransportOptions{LogBodies: true})}).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	_, writable := resp.Body.(io.Writer)
+	assert.True(t, writable, "the upgraded connection stays writable")
+	xtesting.RequireLogged(t, rec, xtesting.AttrEqual("status", http.StatusSwitchingProtocols))
+	xtesting.RequireNotLogged(t, rec, xtesting.HasAttr("response_body"))
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
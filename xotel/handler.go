@@ -52,7 +52,9 @@ func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
 	}
 	traceOptions := []trace.EventOption{
 		trace.WithAttributes(h.convertAttrs(record)...),
-		trace.WithTimestamp(record.Time),
+	}
+	if !record.Time.IsZero() {
+		traceOptions = append(traceOptions, trace.WithTimestamp(record.Time))
 	}
 	if record.Level == slog.LevelError {
 		traceOptions = append(traceOptions, trace.WithStackTrace(true))
@@ -65,9 +67,6 @@ func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	if len(attrs) == 0 {
 		return h
 	}
-	if h.goa == nil {
-		h.goa = new(withsupport.GroupOrAttrs)
-	}
 	return &Handler{
 		enabledLevels: h.enabledLevels,
 		goa:           h.goa.WithAttrs(attrs),
@@ -79,9 +78,6 @@ func (h *Handler) WithGroup(name string) slog.Handler {
 	if len(name) == 0 {
 		return h
 	}
-	if h.goa == nil {
-		h.goa = new(withsupport.GroupOrAttrs)
-	}
 	return &Handler{
 		enabledLevels: h.enabledLevels,
 		goa:           h.goa.WithGroup(name),
@@ -92,17 +88,34 @@ func (h *Handler) WithGroup(name string) slog.Handler {
 func (h *Handler) convertAttrs(record slog.Record) []attribute.KeyValue {
 	attrs := make([]attribute.KeyValue, 0, record.NumAttrs())
 	groups := h.goa.Apply(func(groups []string, attr slog.Attr) {
-		attrs = append(attrs, attribute.KeyValue{
-			Key:   attribute.Key(h.keyBuilder(groups, attr.Key)),
-			Value: attribute.StringValue(attr.Value.String()),
-		})
+		attrs = h.appendAttr(attrs, groups, attr)
 	})
 	record.Attrs(func(attr slog.Attr) bool {
-		attrs = append(attrs, attribute.KeyValue{
+		attrs = h.appendAttr(attrs, groups, attr)
+		return true
+	})
+	return attrs
+}
+
+// appendAttr appends attr to attrs with its value resolved. Groups are flattened into one attribute
+// per member with keys built from the group names, empty attrs and empty groups are dropped,
+// and members of groups with empty keys are added to the enclosing group.
+func (h *Handler) appendAttr(attrs []attribute.KeyValue, groups []string, attr slog.Attr) []attribute.KeyValue {
+	attr.Value = attr.Value.Resolve()
+	if attr.Value.Kind() != slog.KindGroup {
+		if attr.Key == "" && attr.Value.Kind() == slog.KindAny && attr.Value.Any() == nil {
+			return attrs
+		}
+		return append(attrs, attribute.KeyValue{
 			Key:   attribute.Key(h.keyBuilder(groups, attr.Key)),
 			Value: attribute.StringValue(attr.Value.String()),
 		})
-		return true
-	})
+	}
+	if attr.Key != "" {
+		groups = append(groups[:len(groups):len(groups)], attr.Key)
+	}
+	for _, member := range attr.Value.Group() {
+		attrs = h.appendAttr(attrs, groups, member)
+	}
 	return attrs
 }
@@ -0,0 +1,77 @@
+package xotel
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/galecore/xslog/xslogtest"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
+)
+
+// recordingSpan records the events added to it. It is safe for concurrent use.
+type recordingSpan struct {
+	trace.Span
+	mu     sync.Mutex
+	events []map[string]any
+}
+
+func (s *recordingSpan) AddEvent(name string, options ...trace.EventOption) {
+	config := trace.NewEventConfig(options...)
+	event := map[string]any{
+		slog.MessageKey: name,
+		slog.LevelKey:   "", // Handler does not record levels
+	}
+	event[slog.TimeKey] = config.Timestamp().String()
+	for _, attr := range config.Attributes() {
+		// Keys of attrs in groups are joined with DefaultSeparator.
+		path := strings.Split(string(attr.Key), DefaultSeparator)
+		group := event
+		for _, name := range path[:len(path)-1] {
+			if _, ok := group[name].(map[string]any); !ok {
+				group[name] = make(map[string]any)
+			}
+			group = group[name].(map[string]any)
+		}
+		group[path[len(path)-1]] = attr.Value.Emit()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+}
+
+// spanHandler passes a context carrying span to the wrapped handler.
+type spanHandler struct {
+	slog.Handler
+	span trace.Span
+}
+
+func (h *spanHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.Handler.Handle(trace.ContextWithSpan(context.Background(), h.span), record)
+}
+
+func (h *spanHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &spanHandler{Handler: h.Handler.WithAttrs(attrs), span: h.span}
+}
+
+func (h *spanHandler) WithGroup(name string) slog.Handler {
+	return &spanHandler{Handler: h.Handler.WithGroup(name), span: h.span}
+}
+
+func TestHandler_Conformance(t *testing.T) {
+	xslogtest.Run(t, func(t *testing.T) (slog.Handler, func() []map[string]any) {
+		span := &recordingSpan{Span: trace.SpanFromContext(context.Background())}
+		h := NewHandler([]slog.Level{slog.LevelInfo}, DefaultKeyBuilder)
+		return &spanHandler{Handler: h, span: span}, func() []map[string]any {
+			span.mu.Lock()
+			defer span.mu.Unlock()
+			return append([]map[string]any(nil), span.events...)
+		}
+	}, &xslogtest.Options{
+		// The trace API stamps events added without a timestamp with the current time.
+		Skip: []string{"zero-time"},
+	})
+}
@@ -0,0 +1,138 @@
+package xshadow
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// DiffKind classifies a difference between the outputs of the primary and the candidate handler.
+type DiffKind int
+
+const (
+	// DiffMissing is a key output by the primary handler but not by the candidate.
+	DiffMissing DiffKind = iota
+	// DiffExtra is a key output by the candidate handler but not by the primary.
+	DiffExtra
+	// DiffType is a key whose values have different types, e.g. a number and a string.
+	DiffType
+	// DiffValue is a key whose values have the same type but differ.
+	DiffValue
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffMissing:
+		return "missing"
+	case DiffExtra:
+		return "extra"
+	case DiffType:
+		return "type"
+	case DiffValue:
+		return "value"
+	default:
+		return "DiffKind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// Diff is a difference between the outputs of a record.
+type Diff struct {
+	Kind DiffKind
+	// Path lists the keys of the enclosing objects and the key of the value separated by dots,
+	// with array elements identified by their index, e.g. "http.headers.0". It is empty if the candidate
+	// output nothing at all.
+	Path string
+	// Primary and Candidate are the decoded values, or nil if the key is missing.
+	Primary, Candidate any
+}
+
+func (d Diff) String() string {
+	switch d.Kind {
+	case DiffMissing:
+		return fmt.Sprintf("%s: missing, want %v", d.Path, d.Primary)
+	case DiffExtra:
+		return fmt.Sprintf("%s: unexpected %v", d.Path, d.Candidate)
+	default:
+		return fmt.Sprintf("%s: %s mismatch, got %v (%s), want %v (%s)",
+			d.Path, d.Kind, d.Candidate, typeName(d.Candidate), d.Primary, typeName(d.Primary))
+	}
+}
+
+// Compare returns the differences between the trees primary and candidate, sorted by path.
+// Paths in ignore are left out.
+func Compare(primary, candidate map[string]any, ignore map[string]bool) []Diff {
+	var diffs []Diff
+	compareObjects(&diffs, "", primary, candidate, ignore)
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+	return diffs
+}
+
+func compareObjects(diffs *[]Diff, prefix string, primary, candidate map[string]any, ignore map[string]bool) {
+	for key, p := range primary {
+		path := prefix + key
+		if ignore[path] {
+			continue
+		}
+		c, ok := candidate[key]
+		if !ok {
+			*diffs = append(*diffs, Diff{Kind: DiffMissing, Path: path, Primary: p})
+			continue
+		}
+		compareValues(diffs, path, p, c, ignore)
+	}
+	for key, c := range candidate {
+		path := prefix + key
+		if _, ok := primary[key]; !ok && !ignore[path] {
+			*diffs = append(*diffs, Diff{Kind: DiffExtra, Path: path, Candidate: c})
+		}
+	}
+}
+
+func compareValues(diffs *[]Diff, path string, primary, candidate any, ignore map[string]bool) {
+	if ignore[path] {
+		return
+	}
+	if typeName(primary) != typeName(candidate) {
+		*diffs = append(*diffs, Diff{Kind: DiffType, Path: path, Primary: primary, Candidate: candidate})
+		return
+	}
+	switch p := primary.(type) {
+	case map[string]any:
+		compareObjects(diffs, path+".", p, candidate.(map[string]any), ignore)
+	case []any:
+		c := candidate.([]any)
+		if len(p) != len(c) {
+			*diffs = append(*diffs, Diff{Kind: DiffValue, Path: path, Primary: primary, Candidate: candidate})
+			return
+		}
+		for i := range p {
+			compareValues(diffs, path+"."+strconv.Itoa(i), p[i], c[i], ignore)
+		}
+	default:
+		if !reflect.DeepEqual(primary, candidate) {
+			*diffs = append(*diffs, Diff{Kind: DiffValue, Path: path, Primary: primary, Candidate: candidate})
+		}
+	}
+}
+
+// typeName returns the JSON type of a decoded value.
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case string:
+		return "string"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	default:
+		// json.Number, float64 and the other numeric types a custom decoder may return.
+		return "number"
+	}
+}
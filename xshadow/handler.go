@@ -0,0 +1,274 @@
+package xshadow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/galecore/xslog/xdata"
+	"golang.org/x/exp/slog"
+)
+
+// NewHandlerFunc returns a handler that writes the records it handles to w, one Write call or more per record.
+type NewHandlerFunc func(w io.Writer) slog.Handler
+
+// Options configures NewHandler.
+type Options struct {
+	// SampleEvery compares one in every SampleEvery records. Defaults to 1, comparing every record.
+	SampleEvery uint64
+	// Decode parses the output of a single record into a key/value tree. Defaults to DecodeJSON.
+	Decode func(output []byte) (map[string]any, error)
+	// Ignore lists the paths of keys left out of comparisons, e.g. "time". See Diff.Path.
+	Ignore []string
+	// QueueSize is the number of sampled records that may wait for comparison. Records sampled while
+	// the queue is full are dropped and counted as skipped. Defaults to DefaultQueueSize.
+	QueueSize int
+	// OnDiff is called with the differences of every compared record whose outputs differ.
+	// Like OnError, it is called by the goroutine comparing records, off the logging path,
+	// with the context of the Handle call.
+	OnDiff func(ctx context.Context, record slog.Record, diffs []Diff)
+	// OnError is called when the candidate fails to handle a record or an output cannot be decoded.
+	OnError func(ctx context.Context, record slog.Record, err error)
+}
+
+// DefaultQueueSize is the default of Options.QueueSize.
+const DefaultQueueSize = 256
+
+// Stats counts the records seen by a Handler and the handlers derived from it.
+type Stats struct {
+	// Handled is the number of records handled by the primary handler.
+	Handled uint64
+	// Compared is the number of records whose outputs were compared.
+	Compared uint64
+	// Skipped is the number of sampled records not compared because the comparison queue was full
+	// or the handler was closed.
+	Skipped uint64
+	// Mismatched is the number of compared records whose outputs differ.
+	Mismatched uint64
+	// Errors is the number of sampled records that could not be compared.
+	Errors uint64
+}
+
+// Handler sends every record to a primary handler and compares the output of a sample of records
+// with the output of a candidate handler, to verify that a new sink is equivalent to the one it replaces.
+// Comparisons never affect the primary path: Handle returns the error of the primary handler only,
+// sampled records are compared by a background goroutine, records are dropped rather than waited for
+// when it falls behind, and candidate panics are recovered. Close stops the goroutine.
+type Handler struct {
+	primary slog.Handler
+
+	// capture and candidate render sampled records into state.buf for comparison.
+	capture   slog.Handler
+	candidate slog.Handler
+
+	opts   Options
+	ignore map[string]bool
+	state  *state
+}
+
+// state is shared by a Handler and the handlers derived from it.
+type state struct {
+	queue chan comparison
+	stop  chan struct{} // closed by Close
+	done  chan struct{} // closed when the worker returned
+	once  sync.Once
+
+	buf bytes.Buffer // only used by the worker
+
+	handled, compared, skipped, mismatched, errors atomic.Uint64
+}
+
+// comparison is a sampled record waiting for the worker, with the handler that sampled it.
+type comparison struct {
+	h      *Handler
+	ctx    context.Context
+	record slog.Record
+}
+
+// NewHandler returns a handler that writes records to w with the handler returned by newPrimary,
+// and compares their output with the output of the handler returned by newCandidate.
+func NewHandler(w io.Writer, newPrimary, newCandidate NewHandlerFunc, opts *Options) *Handler {
+	h := &Handler{}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.QueueSize <= 0 {
+		h.opts.QueueSize = DefaultQueueSize
+	}
+	h.state = &state{
+		queue: make(chan comparison, h.opts.QueueSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	if h.opts.SampleEvery == 0 {
+		h.opts.SampleEvery = 1
+	}
+	if h.opts.Decode == nil {
+		h.opts.Decode = DecodeJSON
+	}
+	h.ignore = make(map[string]bool, len(h.opts.Ignore))
+	for _, path := range h.opts.Ignore {
+		h.ignore[path] = true
+	}
+	h.primary = newPrimary(w)
+	h.capture = newPrimary(&h.state.buf)
+	h.candidate = newCandidate(&h.state.buf)
+	go h.state.run()
+	return h
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.primary.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	n := h.state.handled.Add(1)
+	if (n-1)%h.opts.SampleEvery != 0 {
+		return h.primary.Handle(ctx, record)
+	}
+	c := comparison{h: h, ctx: ctx, record: record.Clone()}
+	err := h.primary.Handle(ctx, record)
+	select {
+	case <-h.state.stop:
+		h.state.skipped.Add(1)
+	default:
+		select {
+		case h.state.queue <- c:
+		default:
+			h.state.skipped.Add(1)
+		}
+	}
+	return err
+}
+
+func (h *Handler) HandlesContextAttrs() bool {
+	return xdata.HandlesContextAttrs(h.primary)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	handler := *h
+	handler.primary = h.primary.WithAttrs(attrs)
+	handler.capture = h.capture.WithAttrs(attrs)
+	handler.candidate = h.candidate.WithAttrs(attrs)
+	return &handler
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	handler := *h
+	handler.primary = h.primary.WithGroup(name)
+	handler.capture = h.capture.WithGroup(name)
+	handler.candidate = h.candidate.WithGroup(name)
+	return &handler
+}
+
+// Stats returns the counters shared by h and the handlers derived from it.
+func (h *Handler) Stats() Stats {
+	return Stats{
+		Handled:    h.state.handled.Load(),
+		Compared:   h.state.compared.Load(),
+		Skipped:    h.state.skipped.Load(),
+		Mismatched: h.state.mismatched.Load(),
+		Errors:     h.state.errors.Load(),
+	}
+}
+
+// Close compares the records waiting in the queue and stops comparing records. Records handled afterwards
+// are only sent to the primary handler. Close affects h and the handlers derived from it.
+func (h *Handler) Close() error {
+	h.state.once.Do(func() { close(h.state.stop) })
+	<-h.state.done
+	return nil
+}
+
+// run compares queued records until the handler is closed.
+func (s *state) run() {
+	defer close(s.done)
+	for {
+		select {
+		case c := <-s.queue:
+			c.h.compare(c.ctx, c.record)
+		case <-s.stop:
+			for {
+				select {
+				case c := <-s.queue:
+					c.h.compare(c.ctx, c.record)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// compare renders record with both handlers and reports their differences. It is only called by the worker.
+func (h *Handler) compare(ctx context.Context, record slog.Record) {
+	want, err := h.render(ctx, h.capture, record.Clone())
+	if err != nil {
+		h.fail(ctx, record, fmt.Errorf("primary: %w", err))
+		return
+	}
+	if !h.candidate.Enabled(ctx, record.Level) {
+		h.report(ctx, record, []Diff{{Kind: DiffMissing, Primary: want}})
+		return
+	}
+	got, err := h.render(ctx, h.candidate, record)
+	if err != nil {
+		h.fail(ctx, record, fmt.Errorf("candidate: %w", err))
+		return
+	}
+	h.report(ctx, record, Compare(want, got, h.ignore))
+}
+
+// render returns the decoded output of handler for record.
+func (h *Handler) render(ctx context.Context, handler slog.Handler, record slog.Record) (tree map[string]any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	h.state.buf.Reset()
+	if err := handler.Handle(ctx, record); err != nil {
+		return nil, err
+	}
+	return h.opts.Decode(h.state.buf.Bytes())
+}
+
+func (h *Handler) report(ctx context.Context, record slog.Record, diffs []Diff) {
+	h.state.compared.Add(1)
+	if len(diffs) == 0 {
+		return
+	}
+	h.state.mismatched.Add(1)
+	if h.opts.OnDiff != nil {
+		h.opts.OnDiff(ctx, record, diffs)
+	}
+}
+
+func (h *Handler) fail(ctx context.Context, record slog.Record, err error) {
+	h.state.errors.Add(1)
+	if h.opts.OnError != nil {
+		h.opts.OnError(ctx, record, err)
+	}
+}
+
+// DecodeJSON decodes the output of a record written as a JSON object. Numbers are kept as json.Number,
+// so that they are compared exactly.
+func DecodeJSON(output []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(output))
+	dec.UseNumber()
+	var tree map[string]any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, fmt.Errorf("decode %q: %w", output, err)
+	}
+	return tree, nil
+}
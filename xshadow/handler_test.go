@@ -0,0 +1,173 @@
+package xshadow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/galecore/xslog/xslogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+)
+
+func newJSONHandler(w io.Writer) slog.Handler {
+	return slog.NewJSONHandler(w, nil)
+}
+
+// newLegacyHandler writes JSON like newJSONHandler, except that it calls the message "message",
+// formats ints as strings and drops attrs named "dropped".
+func newLegacyHandler(w io.Writer) slog.Handler {
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			switch {
+			case len(groups) == 0 && a.Key == slog.MessageKey:
+				a.Key = "message"
+			case a.Key == "dropped":
+				return slog.Attr{}
+			case a.Value.Kind() == slog.KindInt64:
+				a.Value = slog.StringValue(a.Value.String())
+			}
+			return a
+		},
+	})
+}
+
+type panicHandler struct {
+	slog.Handler
+}
+
+func (panicHandler) Handle(context.Context, slog.Record) error {
+	panic("boom")
+}
+
+func TestHandler(t *testing.T) {
+	t.Run("equivalent", func(t *testing.T) {
+		var buf bytes.Buffer
+		var diffs []Diff
+		h := NewHandler(&buf, newJSONHandler, newJSONHandler, &Options{
+			OnDiff: func(_ context.Context, _ slog.Record, d []Diff) { diffs = append(diffs, d...) },
+		})
+		slog.New(h).With("k", "v").WithGroup("g").Info("test", "n", 1)
+		require.NoError(t, h.Close())
+		assert.Contains(t, buf.String(), `"msg":"test","k":"v","g":{"n":1}}`)
+		assert.Empty(t, diffs)
+		assert.Equal(t, Stats{Handled: 1, Compared: 1}, h.Stats())
+	})
+
+	t.Run("differences", func(t *testing.T) {
+		var buf bytes.Buffer
+		var diffs []Diff
+		h := NewHandler(&buf, newJSONHandler, newLegacyHandler, &Options{
+			Ignore: []string{"time"},
+			OnDiff: func(_ context.Context, _ slog.Record, d []Diff) { diffs = append(diffs, d...) },
+		})
+		slog.New(h).WithGroup("g").Info("test", "n", 1, "dropped", true, "s", "x")
+		require.NoError(t, h.Close())
+		assert.Equal(t, []Diff{
+			{Kind: DiffMissing, Path: "g.dropped", Primary: true},
+			{Kind: DiffType, Path: "g.n", Primary: json.Number("1"), Candidate: "1"},
+			{Kind: DiffExtra, Path: "message", Candidate: "test"},
+			{Kind: DiffMissing, Path: "msg", Primary: "test"},
+		}, diffs)
+		assert.Equal(t, "g.n: type mismatch, got 1 (string), want 1 (number)", diffs[1].String())
+		assert.Equal(t, Stats{Handled: 1, Compared: 1, Mismatched: 1}, h.Stats())
+		assert.Equal(t, 1, bytes.Count(buf.Bytes(), []byte("\n")), "only the primary writes to w")
+	})
+
+	t.Run("sampling", func(t *testing.T) {
+		h := NewHandler(io.Discard, newJSONHandler, newLegacyHandler, &Options{SampleEvery: 3})
+		logger := slog.New(h)
+		for i := 0; i < 7; i++ {
+			logger.Info("test")
+		}
+		require.NoError(t, h.Close())
+		assert.Equal(t, Stats{Handled: 7, Compared: 3, Mismatched: 3}, h.Stats())
+	})
+
+	t.Run("candidate panics", func(t *testing.T) {
+		var buf bytes.Buffer
+		var errs []error
+		h := NewHandler(&buf, newJSONHandler, func(w io.Writer) slog.Handler {
+			return panicHandler{newJSONHandler(w)}
+		}, &Options{
+			OnError: func(_ context.Context, _ slog.Record, err error) { errs = append(errs, err) },
+		})
+		require.NotPanics(t, func() { slog.New(h).Info("test") })
+		require.NoError(t, h.Close())
+		assert.Contains(t, buf.String(), `"msg":"test"`)
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "candidate: handler panicked: boom")
+		assert.Equal(t, Stats{Handled: 1, Errors: 1}, h.Stats())
+	})
+
+	t.Run("candidate disabled", func(t *testing.T) {
+		var diffs []Diff
+		h := NewHandler(io.Discard, newJSONHandler, func(w io.Writer) slog.Handler {
+			return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelWarn})
+		}, &Options{
+			OnDiff: func(_ context.Context, _ slog.Record, d []Diff) { diffs = append(diffs, d...) },
+		})
+		slog.New(h).Info("test")
+		require.NoError(t, h.Close())
+		require.Len(t, diffs, 1)
+		assert.Equal(t, DiffMissing, diffs[0].Kind)
+		assert.Empty(t, diffs[0].Path)
+	})
+
+	t.Run("queue full", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+		var once sync.Once
+		h := NewHandler(io.Discard, newJSONHandler, newLegacyHandler, &Options{
+			QueueSize: 1,
+			OnDiff: func(context.Context, slog.Record, []Diff) {
+				once.Do(func() {
+					close(started)
+					<-release
+				})
+			},
+		})
+		logger := slog.New(h)
+		logger.Info("compared while blocking")
+		<-started
+		logger.Info("queued")
+		logger.Info("dropped")
+		close(release)
+		require.NoError(t, h.Close())
+		logger.Info("closed")
+		assert.Equal(t, Stats{Handled: 4, Compared: 2, Skipped: 2, Mismatched: 2}, h.Stats())
+	})
+}
+
+func TestCompare(t *testing.T) {
+	primary := map[string]any{"a": []any{"x", "y"}, "b": map[string]any{"c": nil}, "d": false}
+	candidate := map[string]any{"a": []any{"x", "z"}, "b": map[string]any{"c": nil}, "d": false}
+	assert.Equal(t, []Diff{{Kind: DiffValue, Path: "a.1", Primary: "y", Candidate: "z"}}, Compare(primary, candidate, nil))
+	assert.Empty(t, Compare(primary, candidate, map[string]bool{"a.1": true}))
+
+	candidate["a"] = []any{"x"}
+	assert.Equal(t, []Diff{{Kind: DiffValue, Path: "a", Primary: primary["a"], Candidate: candidate["a"]}}, Compare(primary, candidate, nil))
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tree, err := DecodeJSON([]byte(`{"n":1.50}` + "\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"n": json.Number("1.50")}, tree)
+
+	_, err = DecodeJSON([]byte("INFO msg"))
+	assert.True(t, err != nil && !errors.Is(err, io.EOF))
+}
+
+func TestHandler_Conformance(t *testing.T) {
+	xslogtest.Run(t, func(t *testing.T) (slog.Handler, func() []map[string]any) {
+		buf := &xslogtest.Buffer{}
+		return NewHandler(buf, newJSONHandler, newJSONHandler, nil), xslogtest.JSONLines(t, buf)
+	}, &xslogtest.Options{
+		// slog.JSONHandler opens groups that end up empty.
+		Skip: []string{"empty-with-group", "empty-nested-with-group"},
+	})
+}
@@ -0,0 +1,45 @@
+package xslogtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+// Buffer is an io.Writer that is safe for concurrent use, for handlers that do not serialize their writes.
+type Buffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// Bytes returns a copy of the bytes written so far.
+func (b *Buffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+// JSONLines returns a results function for handlers that write one JSON object per line to buf.
+// Decoding errors fail t.
+func JSONLines(t *testing.T, buf *Buffer) func() []map[string]any {
+	return func() []map[string]any {
+		var records []map[string]any
+		scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+		for scanner.Scan() {
+			var record map[string]any
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				t.Fatalf("decode %q: %v", scanner.Text(), err)
+			}
+			records = append(records, record)
+		}
+		return records
+	}
+}
@@ -0,0 +1,459 @@
+// Package xslogtest is a conformance suite for slog.Handler implementations. It runs the scenarios of
+// golang.org/x/exp/slog/slogtest as separate subtests, together with cases for nested groups, empty groups,
+// slog.LogValuer resolution and concurrent use.
+package xslogtest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// NewHandlerFunc returns a fresh handler for a single case, together with a function that returns the records
+// output by the handler so far. Each record is a map from keys to values, with groups represented as nested
+// maps and values as strings, like the result of decoding JSON output produced by slog.JSONHandler with only
+// string attrs. The built-in attrs use the keys slog.TimeKey, slog.LevelKey and slog.MessageKey.
+//
+// If a handler intentionally drops an attr that is checked by a case, the results function should add it.
+type NewHandlerFunc func(t *testing.T) (h slog.Handler, results func() []map[string]any)
+
+// Options configures Run.
+type Options struct {
+	// Skip lists the names of cases that the handler knowingly fails. See CaseNames.
+	Skip []string
+}
+
+// Run runs every conformance case as a subtest of t against a handler returned by newHandler.
+func Run(t *testing.T, newHandler NewHandlerFunc, opts *Options) {
+	skip := make(map[string]bool)
+	if opts != nil {
+		for _, name := range opts.Skip {
+			skip[name] = true
+		}
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			if skip[c.name] {
+				t.Skip("skipped by options")
+			}
+			h, results := newHandler(t)
+			if c.mod != nil {
+				h = &modHandler{Handler: h, mod: c.mod}
+			}
+			c.f(slog.New(h))
+
+			records := results()
+			want := c.records
+			if want == 0 {
+				want = 1
+			}
+			if len(records) != want {
+				t.Fatalf("got %d records, want %d: %s", len(records), want, c.explanation)
+			}
+			for _, record := range records {
+				for _, check := range c.checks {
+					if problem := check(record); problem != "" {
+						t.Errorf("%s: %s\nrecord: %v", problem, c.explanation, record)
+					}
+				}
+			}
+			if c.checkAll != nil {
+				if problem := c.checkAll(records); problem != "" {
+					t.Errorf("%s: %s", problem, c.explanation)
+				}
+			}
+		})
+	}
+}
+
+// CaseNames returns the names of all conformance cases in the order they are run.
+func CaseNames() []string {
+	names := make([]string, len(cases))
+	for i, c := range cases {
+		names[i] = c.name
+	}
+	return names
+}
+
+type testCase struct {
+	name        string
+	explanation string
+	f           func(l *slog.Logger)
+	mod         func(r *slog.Record)
+	checks      []check
+	// records is the number of records f logs. Defaults to 1.
+	records  int
+	checkAll func(records []map[string]any) string
+}
+
+const (
+	concurrentWorkers = 8
+	concurrentRecords = 25
+)
+
+var cases = []testCase{
+	{
+		name:        "built-ins",
+		explanation: "a Handler should output slog.TimeKey, slog.LevelKey and slog.MessageKey",
+		f: func(l *slog.Logger) {
+			l.Info("message")
+		},
+		checks: []check{
+			hasKey(slog.TimeKey),
+			hasKey(slog.LevelKey),
+			hasAttr(slog.MessageKey, "message"),
+		},
+	},
+	{
+		name:        "attrs",
+		explanation: "a Handler should output attributes passed to the logging function",
+		f: func(l *slog.Logger) {
+			l.Info("message", "k", "v")
+		},
+		checks: []check{
+			hasAttr("k", "v"),
+		},
+	},
+	{
+		name:        "empty-attr",
+		explanation: "a Handler should ignore an empty Attr",
+		f: func(l *slog.Logger) {
+			l.Info("msg", "a", "b", "", nil, "c", "d")
+		},
+		checks: []check{
+			hasAttr("a", "b"),
+			missingKey(""),
+			hasAttr("c", "d"),
+		},
+	},
+	{
+		name:        "zero-time",
+		explanation: "a Handler should ignore a zero Record.Time",
+		f: func(l *slog.Logger) {
+			l.Info("msg", "k", "v")
+		},
+		mod: func(r *slog.Record) { r.Time = time.Time{} },
+		checks: []check{
+			missingKey(slog.TimeKey),
+		},
+	},
+	{
+		name:        "with-attrs",
+		explanation: "a Handler should include the attributes from the WithAttrs method",
+		f: func(l *slog.Logger) {
+			l.With("a", "b").Info("msg", "k", "v")
+		},
+		checks: []check{
+			hasAttr("a", "b"),
+			hasAttr("k", "v"),
+		},
+	},
+	{
+		name:        "groups",
+		explanation: "a Handler should handle Group attributes",
+		f: func(l *slog.Logger) {
+			l.Info("msg", "a", "b", slog.Group("G", slog.String("c", "d")), "e", "f")
+		},
+		checks: []check{
+			hasAttr("a", "b"),
+			inGroup("G", hasAttr("c", "d")),
+			hasAttr("e", "f"),
+		},
+	},
+	{
+		name:        "empty-group",
+		explanation: "a Handler should ignore an empty group",
+		f: func(l *slog.Logger) {
+			l.Info("msg", "a", "b", slog.Group("G"), "e", "f")
+		},
+		checks: []check{
+			hasAttr("a", "b"),
+			missingKey("G"),
+			hasAttr("e", "f"),
+		},
+	},
+	{
+		name:        "inline-group",
+		explanation: "a Handler should inline the Attrs of a group with an empty key",
+		f: func(l *slog.Logger) {
+			l.Info("msg", "a", "b", slog.Group("", slog.String("c", "d")), "e", "f")
+		},
+		checks: []check{
+			hasAttr("a", "b"),
+			hasAttr("c", "d"),
+			hasAttr("e", "f"),
+		},
+	},
+	{
+		name:        "with-group",
+		explanation: "a Handler should handle the WithGroup method",
+		f: func(l *slog.Logger) {
+			l.WithGroup("G").Info("msg", "a", "b")
+		},
+		checks: []check{
+			hasKey(slog.TimeKey),
+			hasKey(slog.LevelKey),
+			hasAttr(slog.MessageKey, "msg"),
+			missingKey("a"),
+			inGroup("G", hasAttr("a", "b")),
+		},
+	},
+	{
+		name:        "multi-with",
+		explanation: "a Handler should handle multiple WithGroup and WithAttr calls",
+		f: func(l *slog.Logger) {
+			l.With("a", "b").WithGroup("G").With("c", "d").WithGroup("H").Info("msg", "e", "f")
+		},
+		checks: []check{
+			hasKey(slog.TimeKey),
+			hasKey(slog.LevelKey),
+			hasAttr(slog.MessageKey, "msg"),
+			hasAttr("a", "b"),
+			inGroup("G", hasAttr("c", "d")),
+			inGroup("G", inGroup("H", hasAttr("e", "f"))),
+		},
+	},
+	{
+		name:        "resolve",
+		explanation: "a Handler should call Resolve on attribute values",
+		f: func(l *slog.Logger) {
+			l.Info("msg", "k", &replace{"replaced"})
+		},
+		checks: []check{hasAttr("k", "replaced")},
+	},
+	{
+		name:        "resolve-groups",
+		explanation: "a Handler should call Resolve on attribute values in groups",
+		f: func(l *slog.Logger) {
+			l.Info("msg", slog.Group("G", slog.String("a", "v1"), slog.Any("b", &replace{"v2"})))
+		},
+		checks: []check{
+			inGroup("G", hasAttr("a", "v1")),
+			inGroup("G", hasAttr("b", "v2")),
+		},
+	},
+	{
+		name:        "resolve-with-attrs",
+		explanation: "a Handler should call Resolve on attribute values from WithAttrs",
+		f: func(l *slog.Logger) {
+			l.With("k", &replace{"replaced"}).Info("msg")
+		},
+		checks: []check{hasAttr("k", "replaced")},
+	},
+	{
+		name:        "resolve-groups-with-attrs",
+		explanation: "a Handler should call Resolve on attribute values in groups from WithAttrs",
+		f: func(l *slog.Logger) {
+			l.With(slog.Group("G", slog.String("a", "v1"), slog.Any("b", &replace{"v2"}))).Info("msg")
+		},
+		checks: []check{
+			inGroup("G", hasAttr("a", "v1")),
+			inGroup("G", hasAttr("b", "v2")),
+		},
+	},
+	{
+		name:        "with-attrs-between-groups",
+		explanation: "a Handler should nest the attributes from WithAttrs in the groups opened before them",
+		f: func(l *slog.Logger) {
+			l.With("a", "b").WithGroup("G").With("c", "d").WithGroup("H").With("e", "f").Info("msg", "g", "h")
+		},
+		checks: []check{
+			hasAttr("a", "b"),
+			missingKey("c"),
+			inGroup("G", hasAttr("c", "d")),
+			inGroup("G", missingKey("e")),
+			inGroup("G", inGroup("H", hasAttr("e", "f"))),
+			inGroup("G", inGroup("H", hasAttr("g", "h"))),
+		},
+	},
+	{
+		name:        "group-in-with-group",
+		explanation: "a Handler should nest Group attributes in the groups from WithGroup",
+		f: func(l *slog.Logger) {
+			l.WithGroup("G").Info("msg", slog.Group("H", slog.String("a", "b")))
+		},
+		checks: []check{
+			missingKey("H"),
+			inGroup("G", inGroup("H", hasAttr("a", "b"))),
+		},
+	},
+	{
+		name:        "empty-with-group",
+		explanation: "a Handler should not output a group from WithGroup that holds no attributes",
+		f: func(l *slog.Logger) {
+			l.WithGroup("G").Info("msg")
+		},
+		checks: []check{
+			hasAttr(slog.MessageKey, "msg"),
+			missingKey("G"),
+		},
+	},
+	{
+		name:        "empty-nested-with-group",
+		explanation: "a Handler should not output a nested group from WithGroup that holds no attributes",
+		f: func(l *slog.Logger) {
+			l.WithGroup("G").With("a", "b").WithGroup("H").Info("msg")
+		},
+		checks: []check{
+			inGroup("G", hasAttr("a", "b")),
+			inGroup("G", missingKey("H")),
+		},
+	},
+	{
+		name:        "empty-group-with-attrs",
+		explanation: "a Handler should ignore an empty group passed to WithAttrs",
+		f: func(l *slog.Logger) {
+			l.With(slog.Group("G")).Info("msg", "a", "b")
+		},
+		checks: []check{
+			hasAttr("a", "b"),
+			missingKey("G"),
+		},
+	},
+	{
+		name:        "inline-group-with-attrs",
+		explanation: "a Handler should inline the Attrs of a group with an empty key passed to WithAttrs",
+		f: func(l *slog.Logger) {
+			l.With(slog.Group("", slog.String("a", "b"))).Info("msg")
+		},
+		checks: []check{
+			hasAttr("a", "b"),
+			missingKey(""),
+		},
+	},
+	{
+		name:        "resolve-nested-groups",
+		explanation: "a Handler should call Resolve on attribute values in nested groups",
+		f: func(l *slog.Logger) {
+			l.Info("msg", slog.Group("G", slog.Group("H", slog.Any("k", &replace{"replaced"}))))
+		},
+		checks: []check{
+			inGroup("G", inGroup("H", hasAttr("k", "replaced"))),
+		},
+	},
+	{
+		name:        "resolve-to-group",
+		explanation: "a Handler should output a LogValuer that resolves to a group as a group",
+		f: func(l *slog.Logger) {
+			l.Info("msg", slog.Any("G", &replace{slog.GroupValue(slog.String("a", "b"))}))
+		},
+		checks: []check{
+			inGroup("G", hasAttr("a", "b")),
+		},
+	},
+	{
+		name:        "concurrent",
+		explanation: "a Handler and the handlers derived from it should be safe for concurrent use",
+		f: func(l *slog.Logger) {
+			var wg sync.WaitGroup
+			for i := 0; i < concurrentWorkers; i++ {
+				wg.Add(1)
+				go func(worker string) {
+					defer wg.Done()
+					logger := l.With("worker", worker).WithGroup("G")
+					for j := 0; j < concurrentRecords; j++ {
+						logger.Info("msg", "n", strconv.Itoa(j))
+					}
+				}(strconv.Itoa(i))
+			}
+			wg.Wait()
+		},
+		records: concurrentWorkers * concurrentRecords,
+		checks: []check{
+			hasKey("worker"),
+			inGroup("G", hasKey("n")),
+		},
+		checkAll: func(records []map[string]any) string {
+			counts := make(map[any]int)
+			for _, record := range records {
+				counts[record["worker"]]++
+			}
+			for i := 0; i < concurrentWorkers; i++ {
+				if got := counts[strconv.Itoa(i)]; got != concurrentRecords {
+					return fmt.Sprintf("worker %d: got %d records, want %d", i, got, concurrentRecords)
+				}
+			}
+			return ""
+		},
+	},
+}
+
+type check func(map[string]any) string
+
+func hasKey(key string) check {
+	return func(m map[string]any) string {
+		if _, ok := m[key]; !ok {
+			return fmt.Sprintf("missing key %q", key)
+		}
+		return ""
+	}
+}
+
+func missingKey(key string) check {
+	return func(m map[string]any) string {
+		if _, ok := m[key]; ok {
+			return fmt.Sprintf("unexpected key %q", key)
+		}
+		return ""
+	}
+}
+
+func hasAttr(key string, want any) check {
+	return func(m map[string]any) string {
+		if problem := hasKey(key)(m); problem != "" {
+			return problem
+		}
+		if got := m[key]; !reflect.DeepEqual(got, want) {
+			return fmt.Sprintf("%q: got %#v, want %#v", key, got, want)
+		}
+		return ""
+	}
+}
+
+func inGroup(name string, c check) check {
+	return func(m map[string]any) string {
+		v, ok := m[name]
+		if !ok {
+			return fmt.Sprintf("missing group %q", name)
+		}
+		g, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Sprintf("value for group %q is not map[string]any", name)
+		}
+		return c(g)
+	}
+}
+
+// modHandler modifies records before passing them on.
+type modHandler struct {
+	slog.Handler
+	mod func(*slog.Record)
+}
+
+func (h *modHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mod(&r)
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *modHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &modHandler{Handler: h.Handler.WithAttrs(attrs), mod: h.mod}
+}
+
+func (h *modHandler) WithGroup(name string) slog.Handler {
+	return &modHandler{Handler: h.Handler.WithGroup(name), mod: h.mod}
+}
+
+type replace struct {
+	v any
+}
+
+func (r *replace) LogValue() slog.Value {
+	return slog.AnyValue(r.v)
+}
@@ -0,0 +1,254 @@
+package xsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/galecore/xslog/xdata"
+	"golang.org/x/exp/slog"
+)
+
+type conn struct {
+	c    driver.Conn
+	opts Options
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	start := xdata.Now(ctx)
+	var (
+		s   driver.Stmt
+		err error
+	)
+	if cp, ok := c.c.(driver.ConnPrepareContext); ok {
+		s, err = cp.PrepareContext(ctx, query)
+	} else {
+		s, err = c.c.Prepare(query)
+	}
+	if err != nil {
+		c.opts.log(ctx, "sql prepare", start, err, query, nil)
+		return nil, err
+	}
+	return &stmt{s: s, query: query, opts: c.opts}, nil
+}
+
+func (c *conn) Close() error {
+	return c.c.Close()
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := xdata.Now(ctx)
+	var (
+		t   driver.Tx
+		err error
+	)
+	if cb, ok := c.c.(driver.ConnBeginTx); ok {
+		t, err = cb.BeginTx(ctx, opts)
+	} else {
+		t, err = c.c.Begin()
+	}
+	c.opts.log(ctx, "sql begin", start, err, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return &tx{t: t, ctx: ctx, opts: c.opts}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := xdata.Now(ctx)
+	var (
+		res driver.Result
+		err error
+	)
+	switch e := c.c.(type) {
+	case driver.ExecerContext:
+		res, err = e.ExecContext(ctx, query, args)
+	case driver.Execer:
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			res, err = e.Exec(query, values)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	if errors.Is(err, driver.ErrSkip) {
+		return nil, err
+	}
+	c.opts.log(ctx, "sql exec", start, err, query, args, rowsAffected(res)...)
+	return res, err
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := xdata.Now(ctx)
+	var (
+		rows driver.Rows
+		err  error
+	)
+	switch q := c.c.(type) {
+	case driver.QueryerContext:
+		rows, err = q.QueryContext(ctx, query, args)
+	case driver.Queryer:
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = q.Query(query, values)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	if errors.Is(err, driver.ErrSkip) {
+		return nil, err
+	}
+	c.opts.log(ctx, "sql query", start, err, query, args)
+	return rows, err
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.c.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.c.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.c.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := c.c.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type stmt struct {
+	s     driver.Stmt
+	query string
+	opts  Options
+}
+
+func (s *stmt) Close() error {
+	return s.s.Close()
+}
+
+func (s *stmt) NumInput() int {
+	return s.s.NumInput()
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := xdata.Now(ctx)
+	var (
+		res driver.Result
+		err error
+	)
+	if e, ok := s.s.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			res, err = s.s.Exec(values)
+		}
+	}
+	s.opts.log(ctx, "sql exec", start, err, s.query, args, rowsAffected(res)...)
+	return res, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := xdata.Now(ctx)
+	var (
+		rows driver.Rows
+		err  error
+	)
+	if q, ok := s.s.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = s.s.Query(values)
+		}
+	}
+	s.opts.log(ctx, "sql query", start, err, s.query, args)
+	return rows, err
+}
+
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := s.s.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type tx struct {
+	t    driver.Tx
+	ctx  context.Context
+	opts Options
+}
+
+func (t *tx) Commit() error {
+	start := xdata.Now(t.ctx)
+	err := t.t.Commit()
+	t.opts.log(t.ctx, "sql commit", start, err, "", nil)
+	return err
+}
+
+func (t *tx) Rollback() error {
+	start := xdata.Now(t.ctx)
+	err := t.t.Rollback()
+	t.opts.log(t.ctx, "sql rollback", start, err, "", nil)
+	return err
+}
+
+func rowsAffected(res driver.Result) []slog.Attr {
+	if res == nil {
+		return nil
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil
+	}
+	return []slog.Attr{slog.Int64("rows_affected", n)}
+}
+
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("xsql: driver does not support named arguments")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+func valuesToNamedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
@@ -0,0 +1,110 @@
+package xsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// DefaultSlowThreshold is the duration above which statements are logged at slog.LevelWarn.
+const DefaultSlowThreshold = 200 * time.Millisecond
+
+// ArgsMode controls how statement arguments are logged.
+type ArgsMode int
+
+const (
+	// ArgsCount logs only the number of arguments.
+	ArgsCount ArgsMode = iota
+	// ArgsSummary logs the type of every argument, and the length of strings and byte slices.
+	ArgsSummary
+)
+
+// Options configures the logging driver.
+type Options struct {
+	// Level is used for statements that succeeded within SlowThreshold. Defaults to slog.LevelDebug.
+	Level slog.Leveler
+	// SlowThreshold escalates statements that took longer to slog.LevelWarn. Defaults to DefaultSlowThreshold,
+	// negative values disable the escalation.
+	SlowThreshold time.Duration
+	// Args controls how statement arguments are logged. Defaults to ArgsCount.
+	Args ArgsMode
+}
+
+func newOptions(opts *Options) Options {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Level == nil {
+		o.Level = slog.LevelDebug
+	}
+	if o.SlowThreshold == 0 {
+		o.SlowThreshold = DefaultSlowThreshold
+	}
+	return o
+}
+
+// Wrap returns a driver that logs the statements and transactions of every connection opened by d.
+// The result is meant to be registered with sql.Register.
+func Wrap(d driver.Driver, opts *Options) driver.Driver {
+	o := newOptions(opts)
+	if dc, ok := d.(driver.DriverContext); ok {
+		return &driverContext{wrappedDriver: wrappedDriver{d: d, opts: o}, dc: dc}
+	}
+	return &wrappedDriver{d: d, opts: o}
+}
+
+// WrapConnector returns a connector that logs the statements and transactions of every
+// connection opened by c. The result is meant to be passed to sql.OpenDB.
+func WrapConnector(c driver.Connector, opts *Options) driver.Connector {
+	return &connector{c: c, opts: newOptions(opts)}
+}
+
+type wrappedDriver struct {
+	d    driver.Driver
+	opts Options
+}
+
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.d.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{c: c, opts: d.opts}, nil
+}
+
+type driverContext struct {
+	wrappedDriver
+	dc driver.DriverContext
+}
+
+func (d *driverContext) OpenConnector(name string) (driver.Connector, error) {
+	c, err := d.dc.OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return &connector{c: c, d: d, opts: d.opts}, nil
+}
+
+type connector struct {
+	c    driver.Connector
+	d    driver.Driver
+	opts Options
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.c.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{c: dc, opts: c.opts}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	if c.d != nil {
+		return c.d
+	}
+	return &wrappedDriver{d: c.c.Driver(), opts: c.opts}
+}
@@ -0,0 +1,286 @@
+package xsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/galecore/xslog"
+	"github.com/galecore/xslog/xdata"
+	"github.com/galecore/xslog/xtesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+)
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{}, nil
+}
+
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{}, nil
+}
+
+func (fakeConnector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeConn struct{}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	return fakeExec(ctx, query)
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	return fakeQuery(query)
+}
+
+type fakeStmt struct {
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return fakeExec(context.Background(), s.query)
+}
+
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return fakeQuery(s.query)
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error {
+	return nil
+}
+
+func (fakeTx) Rollback() error {
+	return errors.New("rollback failed")
+}
+
+type fakeRows struct {
+	done bool
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"n"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+// fakeExec advances the fake clock of ctx by a second for queries starting with SLOW.
+func fakeExec(ctx context.Context, query string) (driver.Result, error) {
+	if strings.HasPrefix(query, "FAIL") {
+		return nil, errors.New("syntax error")
+	}
+	if clock, ok := xdata.ContextClock(ctx).(*xtesting.FakeClock); ok && strings.HasPrefix(query, "SLOW") {
+		clock.Advance(time.Second)
+	}
+	return driver.RowsAffected(3), nil
+}
+
+func fakeQuery(query string) (driver.Rows, error) {
+	if strings.HasPrefix(query, "FAIL") {
+		return nil, errors.New("syntax error")
+	}
+	return &fakeRows{}, nil
+}
+
+func newTestDB(opts *Options) *sql.DB {
+	return sql.OpenDB(WrapConnector(fakeConnector{}, opts))
+}
+
+// newTestContext returns a context that logs to the returned recorder and whose clock only moves
+// when fakeExec runs a slow query.
+func newTestContext() (context.Context, *xtesting.Recorder) {
+	r := xtesting.NewRecorder()
+	ctx := xslog.WithLogger(context.Background(), slog.New(xdata.NewHandler(r)))
+	ctx = xdata.WithClock(ctx, xtesting.NewFakeClock(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), 0))
+	return xdata.WithAttrs(ctx, slog.String("request_id", "abc")), r
+}
+
+func TestWrap(t *testing.T) {
+	sql.Register("xsql-fake", Wrap(fakeDriver{}, nil))
+	db, err := sql.Open("xsql-fake", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx, r := newTestContext()
+	_, err = db.ExecContext(ctx, "UPDATE users SET name = 'bob' WHERE id = ?", 1)
+	require.NoError(t, err)
+	xtesting.RequireCount(t, r, 1)
+	xtesting.RequireLogged(t, r,
+		xtesting.WithLevel(slog.LevelDebug),
+		xtesting.MessageMatches("^sql exec$"),
+		xtesting.AttrEqual("query", "UPDATE users SET name = ? WHERE id = ?"),
+		xtesting.AttrEqual("args", 1),
+		xtesting.AttrEqual("rows_affected", int64(3)),
+		xtesting.AttrEqual("duration", time.Duration(0)),
+		xtesting.AttrEqual("request_id", "abc"),
+	)
+}
+
+func TestConn_ExecContext(t *testing.T) {
+	t.Run("summary", func(t *testing.T) {
+		db := newTestDB(&Options{Args: ArgsSummary})
+		defer db.Close()
+
+		ctx, r := newTestContext()
+		_, err := db.ExecContext(ctx, "INSERT INTO users (name, age) VALUES (?, ?)", "alice", 42)
+		require.NoError(t, err)
+		xtesting.RequireLogged(t, r,
+			xtesting.WithLevel(slog.LevelDebug),
+			xtesting.AttrEqual("query", "INSERT INTO users (name, age) VALUES (?, ?)"),
+			xtesting.AttrEqual("args", []string{"string(5)", "int64"}),
+		)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		db := newTestDB(nil)
+		defer db.Close()
+
+		ctx, r := newTestContext()
+		_, err := db.ExecContext(ctx, "FAIL")
+		assert.Error(t, err)
+		xtesting.RequireLogged(t, r,
+			xtesting.WithLevel(slog.LevelError),
+			xtesting.AttrEqual("query", "FAIL"),
+			xtesting.AttrEqual("error", "syntax error"),
+		)
+		xtesting.RequireNotLogged(t, r, xtesting.HasAttr("rows_affected"))
+	})
+
+	t.Run("slow", func(t *testing.T) {
+		db := newTestDB(&Options{SlowThreshold: time.Millisecond})
+		defer db.Close()
+
+		ctx, r := newTestContext()
+		_, err := db.ExecContext(ctx, "SLOW")
+		require.NoError(t, err)
+		_, err = db.ExecContext(ctx, "UPDATE users SET name = 'bob'")
+		require.NoError(t, err)
+		xtesting.RequireLogged(t, r,
+			xtesting.WithLevel(slog.LevelWarn),
+			xtesting.AttrEqual("query", "SLOW"),
+			xtesting.AttrEqual("duration", time.Second),
+			xtesting.AttrEqual("slow", true),
+		)
+		xtesting.RequireCount(t, r, 1, xtesting.WithLevel(slog.LevelDebug), xtesting.AttrEqual("duration", time.Duration(0)))
+		xtesting.RequireCount(t, r, 1, xtesting.HasAttr("slow"))
+	})
+
+	t.Run("level", func(t *testing.T) {
+		db := newTestDB(&Options{Level: slog.LevelInfo, SlowThreshold: -1})
+		defer db.Close()
+
+		ctx, r := newTestContext()
+		_, err := db.ExecContext(ctx, "SLOW")
+		require.NoError(t, err)
+		xtesting.RequireCount(t, r, 1)
+		xtesting.RequireLogged(t, r, xtesting.WithLevel(slog.LevelInfo), xtesting.AttrEqual("duration", time.Second))
+		xtesting.RequireNotLogged(t, r, xtesting.HasAttr("slow"))
+	})
+}
+
+func TestConn_QueryContext(t *testing.T) {
+	db := newTestDB(nil)
+	defer db.Close()
+
+	ctx, r := newTestContext()
+	var n int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT n FROM numbers WHERE n > 0").Scan(&n))
+	assert.Equal(t, 1, n)
+	xtesting.RequireLogged(t, r,
+		xtesting.WithLevel(slog.LevelDebug),
+		xtesting.MessageMatches("^sql query$"),
+		xtesting.AttrEqual("query", "SELECT n FROM numbers WHERE n > ?"),
+		xtesting.AttrEqual("args", 0),
+	)
+}
+
+func TestStmt(t *testing.T) {
+	db := newTestDB(nil)
+	defer db.Close()
+
+	ctx, r := newTestContext()
+	stmt, err := db.PrepareContext(ctx, "DELETE FROM users WHERE id = ?")
+	require.NoError(t, err)
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, 7)
+	require.NoError(t, err)
+	xtesting.RequireLogged(t, r,
+		xtesting.MessageMatches("^sql exec$"),
+		xtesting.AttrEqual("query", "DELETE FROM users WHERE id = ?"),
+		xtesting.AttrEqual("args", 1),
+		xtesting.AttrEqual("rows_affected", int64(3)),
+	)
+}
+
+func TestTx(t *testing.T) {
+	db := newTestDB(nil)
+	defer db.Close()
+
+	t.Run("commit", func(t *testing.T) {
+		ctx, r := newTestContext()
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+
+		records := r.Records()
+		require.Len(t, records, 2)
+		assert.Equal(t, "DEBUG: sql begin [duration=0s request_id=abc]", records[0].String())
+		assert.Equal(t, "DEBUG: sql commit [duration=0s request_id=abc]", records[1].String())
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		ctx, r := newTestContext()
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		assert.Error(t, tx.Rollback())
+		xtesting.RequireLogged(t, r,
+			xtesting.WithLevel(slog.LevelError),
+			xtesting.MessageMatches("^sql rollback$"),
+			xtesting.AttrEqual("error", "rollback failed"),
+		)
+	})
+}
@@ -0,0 +1,185 @@
+package xsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/galecore/xslog"
+	"github.com/galecore/xslog/xdata"
+	"golang.org/x/exp/slog"
+)
+
+func (o Options) log(ctx context.Context, msg string, start time.Time, err error, query string, args []driver.NamedValue, attrs ...slog.Attr) {
+	duration := xdata.Since(ctx, start)
+	level := o.Level.Level()
+	slow := o.SlowThreshold > 0 && duration >= o.SlowThreshold
+	if slow {
+		level = slog.LevelWarn
+	}
+	if err != nil {
+		level = slog.LevelError
+	}
+
+	if !xslog.Logger(ctx).Enabled(ctx, level) {
+		return
+	}
+	if query != "" {
+		attrs = append([]slog.Attr{slog.String("query", NormalizeQuery(query)), o.argsAttr(args)}, attrs...)
+	}
+	attrs = append(attrs, slog.Duration("duration", duration))
+	if slow {
+		attrs = append(attrs, slog.Bool("slow", true))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	xslog.Log(ctx, level, msg, attrs)
+}
+
+func (o Options) argsAttr(args []driver.NamedValue) slog.Attr {
+	if o.Args != ArgsSummary {
+		return slog.Int("args", len(args))
+	}
+	summary := make([]string, len(args))
+	for i, arg := range args {
+		summary[i] = summarizeValue(arg.Value)
+	}
+	return slog.Any("args", summary)
+}
+
+func summarizeValue(v driver.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return fmt.Sprintf("string(%d)", len(v))
+	case []byte:
+		return fmt.Sprintf("[]byte(%d)", len(v))
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// NormalizeQuery collapses whitespace in query and replaces string and numeric literals with "?",
+// so that logged queries neither leak inlined values nor differ between executions.
+// Quoted identifiers such as "tab 1" and `tab 1` are kept as they are.
+func NormalizeQuery(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	runes := []rune(query)
+	space := false
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+
+		switch {
+		case r == '\'':
+			i = skipQuoted(runes, i)
+			b.WriteByte('?')
+		case r == '"' || r == '`':
+			end := skipQuoted(runes, i)
+			if end == len(runes) {
+				end--
+			}
+			b.WriteString(string(runes[i : end+1]))
+			i = end
+		case isNumberStart(runes, i):
+			i = skipNumber(runes, i)
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// skipQuoted returns the index of the quote that closes the quoted string or identifier starting at i,
+// treating doubled quotes as escaped, or len(runes) if it is not closed.
+func skipQuoted(runes []rune, i int) int {
+	quote := runes[i]
+	for i++; i < len(runes); i++ {
+		if runes[i] == quote {
+			if i+1 < len(runes) && runes[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return i
+}
+
+// isNumberStart reports whether a numeric literal such as 42, .5 or 0x1F starts at i.
+func isNumberStart(runes []rune, i int) bool {
+	if i > 0 && (isIdentRune(runes[i-1]) || runes[i-1] == '.') {
+		return false
+	}
+	if isASCIIDigit(runes[i]) {
+		return true
+	}
+	return runes[i] == '.' && i+1 < len(runes) && isASCIIDigit(runes[i+1])
+}
+
+// skipNumber returns the index of the last rune of the numeric literal starting at i,
+// including a hex prefix, a fraction and an exponent.
+func skipNumber(runes []rune, i int) int {
+	next := func(j int) rune {
+		if j < len(runes) {
+			return runes[j]
+		}
+		return 0
+	}
+	if runes[i] == '0' && (next(i+1) == 'x' || next(i+1) == 'X') && isHexDigit(next(i+2)) {
+		i += 2
+		for isHexDigit(next(i + 1)) {
+			i++
+		}
+		return i
+	}
+	for isASCIIDigit(next(i + 1)) {
+		i++
+	}
+	if next(i+1) == '.' {
+		i++
+		for isASCIIDigit(next(i + 1)) {
+			i++
+		}
+	}
+	if e := next(i + 1); e == 'e' || e == 'E' {
+		j := i + 2
+		if sign := next(j); sign == '+' || sign == '-' {
+			j++
+		}
+		if isASCIIDigit(next(j)) {
+			i = j
+			for isASCIIDigit(next(i + 1)) {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+func isASCIIDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
+func isHexDigit(r rune) bool {
+	return isASCIIDigit(r) || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F'
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
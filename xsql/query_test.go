@@ -0,0 +1,63 @@
+package xsql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "whitespace",
+			query: "\n  SELECT *\n\tFROM users   WHERE id = $1 \n",
+			want:  "SELECT * FROM users WHERE id = $1",
+		},
+		{
+			name:  "string literals",
+			query: "SELECT * FROM users WHERE name = 'o''brien' AND role = 'admin'",
+			want:  "SELECT * FROM users WHERE name = ? AND role = ?",
+		},
+		{
+			name:  "numeric literals",
+			query: "SELECT * FROM t2 WHERE score > 10.5 LIMIT 20",
+			want:  "SELECT * FROM t2 WHERE score > ? LIMIT ?",
+		},
+		{
+			name:  "hex, exponent and fraction literals",
+			query: "SELECT 0x1F, 1e10, 2.5E-3, .5, 3. FROM t WHERE flags & 0XFF = 0",
+			want:  "SELECT ?, ?, ?, ?, ? FROM t WHERE flags & ? = ?",
+		},
+		{
+			name:  "quoted identifiers",
+			query: `SELECT "col 1", ` + "`tab 2`" + ` FROM "tab ""3""" WHERE "x1" = 4`,
+			want:  `SELECT "col 1", ` + "`tab 2`" + ` FROM "tab ""3""" WHERE "x1" = ?`,
+		},
+		{
+			name:  "unterminated literals",
+			query: `SELECT 'abc`,
+			want:  `SELECT ?`,
+		},
+		{
+			name:  "placeholders",
+			query: "INSERT INTO users (name, age) VALUES (?, ?)",
+			want:  "INSERT INTO users (name, age) VALUES (?, ?)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeQuery(tt.query))
+		})
+	}
+}
+
+func TestSummarizeValue(t *testing.T) {
+	assert.Equal(t, "nil", summarizeValue(nil))
+	assert.Equal(t, "string(5)", summarizeValue("alice"))
+	assert.Equal(t, "[]byte(3)", summarizeValue([]byte{1, 2, 3}))
+	assert.Equal(t, "int64", summarizeValue(int64(42)))
+}
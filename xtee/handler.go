@@ -3,6 +3,7 @@ package xtee
 import (
 	"context"
 
+	"github.com/galecore/xslog/xdata"
 	"golang.org/x/exp/slog"
 )
 
@@ -26,9 +27,22 @@ func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
 }
 
 func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
-	for _, handler := range h.handlers {
+	var contextAttrs []slog.Attr
+	if h.HandlesContextAttrs() {
+		contextAttrs = xdata.ContextAttrs(ctx)
+	}
+	for i, handler := range h.handlers {
 		if handler.Enabled(ctx, record.Level) {
-			if err := handler.Handle(ctx, record); err != nil {
+			addContextAttrs := len(contextAttrs) > 0 && !xdata.HandlesContextAttrs(handler)
+			r := record
+			if i < len(h.handlers)-1 || addContextAttrs {
+				// Handlers may add attrs to their record, which must not leak into the records of the others.
+				r = record.Clone()
+			}
+			if addContextAttrs {
+				r.AddAttrs(contextAttrs...)
+			}
+			if err := handler.Handle(ctx, r); err != nil {
 				return err
 			}
 		}
@@ -36,6 +50,17 @@ func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
 	return nil
 }
 
+// HandlesContextAttrs reports whether any of the handlers adds the attrs bound with xdata.WithAttrs by itself.
+// If so, xslog leaves the attrs to h, and Handle adds them to the records of the other handlers.
+func (h *Handler) HandlesContextAttrs() bool {
+	for _, handler := range h.handlers {
+		if xdata.HandlesContextAttrs(handler) {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	handlers := make([]slog.Handler, len(h.handlers))
 	for i, handler := range h.handlers {
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/galecore/xslog/util"
+	"github.com/galecore/xslog/xslogtest"
 	"github.com/galecore/xslog/xtesting"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/exp/slog"
@@ -92,3 +93,14 @@ func TestHandler_WithGroup(t *testing.T) {
 	assert.Equal(t, "DEBUG: test [l1.group.key=value l1.group.int=1]INFO: test [l1.group.key=value l1.group.int=1]WARN: test [l1.group.key=value l1.group.int=1]ERROR: test [l1.group.key=value l1.group.int=1]", l1.B.String())
 	assert.Equal(t, "DEBUG: test [l2.group.key=value l2.group.int=1]INFO: test [l2.group.key=value l2.group.int=1]WARN: test [l2.group.key=value l2.group.int=1]ERROR: test [l2.group.key=value l2.group.int=1]", l2.B.String())
 }
+
+func TestHandler_Conformance(t *testing.T) {
+	xslogtest.Run(t, func(t *testing.T) (slog.Handler, func() []map[string]any) {
+		r1, r2 := xtesting.NewRecorder(), xtesting.NewRecorder()
+		return NewHandler(r1, r2), func() []map[string]any {
+			results := r1.Results()
+			assert.Equal(t, len(results), len(r2.Results()), "both handlers should receive every record")
+			return results
+		}
+	}, nil)
+}
@@ -0,0 +1,59 @@
+package xtesting
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FakeClock is an xdata.Clock for tests whose time only moves by a fixed step per reading
+// or when moved explicitly. It is safe for concurrent use.
+type FakeClock struct {
+	mu   sync.Mutex
+	now  time.Time
+	step time.Duration
+}
+
+// NewFakeClock returns a clock that starts at start and advances by step after every reading.
+func NewFakeClock(start time.Time, step time.Duration) *FakeClock {
+	return &FakeClock{now: start, step: step}
+}
+
+// Now returns the current time of c and advances it by its step.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now
+	c.now = c.now.Add(c.step)
+	return now
+}
+
+// Advance moves the time of c forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the time of c to t.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// SequentialIDs is an xdata.IDSource for tests that returns "prefix-1", "prefix-2" and so on.
+// It is safe for concurrent use.
+type SequentialIDs struct {
+	prefix string
+	n      atomic.Uint64
+}
+
+func NewSequentialIDs(prefix string) *SequentialIDs {
+	return &SequentialIDs{prefix: prefix}
+}
+
+func (s *SequentialIDs) NewID() string {
+	return fmt.Sprintf("%s-%d", s.prefix, s.n.Add(1))
+}
@@ -0,0 +1,259 @@
+package xtesting
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slog"
+)
+
+// UpdateEnv is the environment variable that makes RequireGolden rewrite golden files when set to a true value,
+// e.g. `XSLOG_UPDATE_GOLDEN=1 go test ./...`.
+const UpdateEnv = "XSLOG_UPDATE_GOLDEN"
+
+// UpdateFlag is the name of a boolean flag that also makes RequireGolden rewrite golden files if the test
+// binary registers it, e.g. with `flag.Bool("update", false, "update golden files")` and `go test ./... -update`.
+// xtesting does not register the flag itself.
+const UpdateFlag = "update"
+
+// Placeholders of masked values in snapshots.
+const (
+	MaskedTime     = "<time>"
+	MaskedDuration = "<duration>"
+	MaskedUUID     = "<uuid>"
+	MaskedValue    = "<masked>"
+)
+
+var uuidPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+// SnapshotFormat chooses how Snapshot renders records.
+type SnapshotFormat int
+
+const (
+	// SnapshotText renders one record per line like Handler, quoting values that contain spaces or quotes.
+	SnapshotText SnapshotFormat = iota
+	// SnapshotJSON renders an indented JSON array with one object per record, with keys sorted.
+	SnapshotJSON
+)
+
+// SnapshotOptions configures Snapshot and RequireGolden.
+type SnapshotOptions struct {
+	Format SnapshotFormat
+	// Mask lists the paths of additional volatile attrs whose values are replaced with MaskedValue. See Record.Attr.
+	Mask []string
+	// Dir is the directory of golden files. Defaults to "testdata".
+	Dir string
+	// Update makes RequireGolden rewrite golden files, like UpdateEnv and UpdateFlag.
+	Update bool
+}
+
+// Snapshot renders records in a stable canonical form. Record times and PCs are omitted,
+// and time values, durations and UUIDs within strings are masked.
+func Snapshot(records []Record, opts *SnapshotOptions) []byte {
+	var o SnapshotOptions
+	if opts != nil {
+		o = *opts
+	}
+	masked := make(map[string]bool, len(o.Mask))
+	for _, path := range o.Mask {
+		masked[path] = true
+	}
+
+	if o.Format == SnapshotJSON {
+		objects := make([]map[string]any, len(records))
+		for i, record := range records {
+			objects[i] = map[string]any{
+				"level": record.Level.String(),
+				"msg":   record.Message,
+				"attrs": jsonAttrs("", record.Attrs, masked),
+			}
+		}
+		var b bytes.Buffer
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(objects); err != nil {
+			panic(err) // values are masked or strings, so they always marshal
+		}
+		return b.Bytes()
+	}
+
+	var b bytes.Buffer
+	for _, record := range records {
+		fmt.Fprintf(&b, "%s: %s [", record.Level, quote(record.Message))
+		textAttrs(&b, "", record.Attrs, masked, true)
+		b.WriteString("]\n")
+	}
+	return b.Bytes()
+}
+
+// RequireGolden fails the test immediately unless the snapshot of the records of r equals the content
+// of the golden file name.golden. If opts.Update, UpdateEnv or UpdateFlag is set, the file is rewritten instead.
+func RequireGolden(t TestingT, r *Recorder, name string, opts *SnapshotOptions) {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	got := Snapshot(r.Records(), opts)
+	dir := "testdata"
+	if opts != nil && opts.Dir != "" {
+		dir = opts.Dir
+	}
+	path := filepath.Join(dir, name+".golden")
+
+	if updateGolden(opts) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Errorf("create golden file directory: %v", err)
+			t.FailNow()
+			return
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Errorf("write golden file: %v", err)
+			t.FailNow()
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("read golden file: %v (run the test with %s=1 to create it)", err, UpdateEnv)
+		t.FailNow()
+		return
+	}
+	if !bytes.Equal(want, got) {
+		t.Errorf("log output differs from %s (run the test with %s=1 to accept it):\n%s",
+			path, UpdateEnv, lineDiff(string(want), string(got)))
+		t.FailNow()
+	}
+}
+
+func updateGolden(opts *SnapshotOptions) bool {
+	if opts != nil && opts.Update {
+		return true
+	}
+	if update, err := strconv.ParseBool(os.Getenv(UpdateEnv)); err == nil && update {
+		return true
+	}
+	f := flag.Lookup(UpdateFlag)
+	if f == nil {
+		return false
+	}
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+	update, _ := getter.Get().(bool)
+	return update
+}
+
+func maskValue(path string, v slog.Value, masked map[string]bool) (string, bool) {
+	switch {
+	case masked[path]:
+		return MaskedValue, true
+	case v.Kind() == slog.KindTime:
+		return MaskedTime, true
+	case v.Kind() == slog.KindDuration:
+		return MaskedDuration, true
+	}
+	return uuidPattern.ReplaceAllString(v.String(), MaskedUUID), false
+}
+
+func textAttrs(b *bytes.Buffer, prefix string, attrs []slog.Attr, masked map[string]bool, first bool) bool {
+	for _, a := range attrs {
+		if a.Value.Kind() == slog.KindGroup {
+			first = textAttrs(b, prefix+a.Key+".", a.Value.Group(), masked, first)
+			continue
+		}
+		if !first {
+			b.WriteString(" ")
+		}
+		value, isMask := maskValue(prefix+a.Key, a.Value, masked)
+		if !isMask {
+			value = quote(value)
+		}
+		fmt.Fprintf(b, "%s%s=%s", prefix, a.Key, value)
+		first = false
+	}
+	return first
+}
+
+func jsonAttrs(prefix string, attrs []slog.Attr, masked map[string]bool) map[string]any {
+	m := make(map[string]any, len(attrs))
+	for _, a := range attrs {
+		if a.Value.Kind() == slog.KindGroup {
+			m[a.Key] = jsonAttrs(prefix+a.Key+".", a.Value.Group(), masked)
+			continue
+		}
+		value, isMask := maskValue(prefix+a.Key, a.Value, masked)
+		if !isMask {
+			switch a.Value.Kind() {
+			case slog.KindBool, slog.KindInt64, slog.KindUint64:
+				m[a.Key] = json.RawMessage(value)
+				continue
+			case slog.KindFloat64:
+				if f := a.Value.Float64(); !math.IsNaN(f) && !math.IsInf(f, 0) {
+					m[a.Key] = json.RawMessage(value)
+					continue
+				}
+			}
+		}
+		m[a.Key] = value
+	}
+	return m
+}
+
+// quote quotes s if it is empty or contains characters that would make a snapshot line ambiguous.
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"[]=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// lineDiff returns the lines of want missing from got prefixed with "-" and
+// the lines of got missing from want prefixed with "+", based on their longest common subsequence.
+func lineDiff(want, got string) string {
+	a := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			out.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			out.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+	return out.String()
+}
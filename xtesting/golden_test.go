@@ -0,0 +1,89 @@
+package xtesting
+
+import (
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+)
+
+func recordFlow(r *Recorder) {
+	logger := slog.New(r).With("service", "billing")
+	logger.Info("invoice created",
+		slog.String("invoice_id", "5f0c6f7e-3c1b-4b8e-9d4a-1a2b3c4d5e6f"),
+		slog.Group("amount", slog.Int("cents", 1250), slog.String("currency", "EUR")),
+		slog.Time("due", time.Now().Add(time.Hour)),
+		slog.Float64("ratio", math.NaN()),
+	)
+	logger.Warn("slow pdf render", slog.Duration("elapsed", 3*time.Second), slog.String("note", "took \"long\""))
+}
+
+func TestRequireGolden(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		r := NewRecorder()
+		recordFlow(r)
+		RequireGolden(t, r, "flow", nil)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		r := NewRecorder()
+		recordFlow(r)
+		RequireGolden(t, r, "flow_json", &SnapshotOptions{Format: SnapshotJSON, Mask: []string{"service"}})
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		if updateGolden(nil) {
+			t.Skip("golden files are being updated")
+		}
+		dir := t.TempDir()
+		expected := NewRecorder()
+		recordFlow(expected)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "flow.golden"), Snapshot(expected.Records(), nil), 0o644))
+
+		r := NewRecorder()
+		slog.New(r).Info("invoice created", "service", "billing")
+		ft := &fakeT{}
+		RequireGolden(ft, r, "flow", &SnapshotOptions{Dir: dir})
+		assert.True(t, ft.failed)
+		assert.Equal(t, []string{`log output differs from ` + filepath.Join(dir, "flow.golden") + ` (run the test with XSLOG_UPDATE_GOLDEN=1 to accept it):
+- INFO: "invoice created" [service=billing invoice_id=<uuid> amount.cents=1250 amount.currency=EUR due=<time> ratio=NaN]
+- WARN: "slow pdf render" [service=billing elapsed=<duration> note="took \"long\""]
++ INFO: "invoice created" [service=billing]
+`}, ft.errors)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		if updateGolden(nil) {
+			t.Skip("golden files are being updated")
+		}
+		ft := &fakeT{}
+		RequireGolden(ft, NewRecorder(), "missing", &SnapshotOptions{Dir: t.TempDir()})
+		assert.True(t, ft.failed)
+		require.Len(t, ft.errors, 1)
+		assert.Contains(t, ft.errors[0], "run the test with XSLOG_UPDATE_GOLDEN=1 to create it")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		assert.Nil(t, flag.Lookup(UpdateFlag), "the flag is left to the test binary")
+		dir := t.TempDir()
+		r := NewRecorder()
+		recordFlow(r)
+		RequireGolden(t, r, "flow", &SnapshotOptions{Dir: dir, Update: true})
+		got, err := os.ReadFile(filepath.Join(dir, "flow.golden"))
+		require.NoError(t, err)
+		assert.Equal(t, Snapshot(r.Records(), nil), got)
+
+		t.Setenv(UpdateEnv, "true")
+		slog.New(r).Info("appended")
+		RequireGolden(t, r, "flow", &SnapshotOptions{Dir: dir})
+		got, err = os.ReadFile(filepath.Join(dir, "flow.golden"))
+		require.NoError(t, err)
+		assert.Equal(t, Snapshot(r.Records(), nil), got)
+	})
+}
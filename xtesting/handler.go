@@ -1,8 +1,11 @@
 package xtesting
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/jba/slog/withsupport"
@@ -14,20 +17,64 @@ type Logger interface {
 	Logf(format string, args ...any)
 }
 
+// Format chooses how Handler prints records.
+type Format int
+
+const (
+	// FormatText prints records as "LEVEL: msg [k=v g.k=v]".
+	FormatText Format = iota
+	// FormatJSON prints records as JSON objects like slog.JSONHandler, without times.
+	FormatJSON
+)
+
+// HandlerOptions configures NewHandlerWithOptions.
+type HandlerOptions struct {
+	// Level is the minimum level of printed records. If nil, all records are printed.
+	Level slog.Leveler
+	// AddSource prints the file:line of the log call before each record.
+	AddSource bool
+	// Format chooses how records are printed. Defaults to FormatText.
+	Format Format
+}
+
 type Handler struct {
-	t   Logger
-	goa *withsupport.GroupOrAttrs
+	t    Logger
+	opts HandlerOptions
+	goa  *withsupport.GroupOrAttrs
+	// json is the slog.JSONHandler used by FormatJSON, with the attrs and groups of goa applied.
+	json slog.Handler
 }
 
 func NewHandler(t Logger) *Handler {
 	return &Handler{t: t}
 }
 
-func (h *Handler) Enabled(context.Context, slog.Level) bool {
-	return true
+func NewHandlerWithOptions(t Logger, opts *HandlerOptions) *Handler {
+	h := &Handler{t: t}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.Format == FormatJSON {
+		h.json = slog.NewJSONHandler(loggerWriter{t}, &slog.HandlerOptions{
+			AddSource:   h.opts.AddSource,
+			ReplaceAttr: replaceJSONAttr,
+		})
+	}
+	return h
 }
 
-func (h *Handler) Handle(_ context.Context, record slog.Record) error {
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.opts.Level == nil || level >= h.opts.Level.Level()
+}
+
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	if h.json != nil {
+		return h.json.Handle(ctx, record)
+	}
+	if h.opts.AddSource && record.PC != 0 {
+		h.t.Logf("%s: %s: %s [%s]", source(record.PC), record.Level, record.Message, h.buildAttrs(record))
+		return nil
+	}
 	h.t.Logf("%s: %s [%s]", record.Level, record.Message, h.buildAttrs(record))
 	return nil
 }
@@ -36,54 +83,106 @@ func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	if len(attrs) == 0 {
 		return h
 	}
-	if h.goa == nil {
-		h.goa = new(withsupport.GroupOrAttrs)
-	}
-	return &Handler{
-		t:   h.t,
-		goa: h.goa.WithAttrs(attrs),
+	handler := &Handler{t: h.t, opts: h.opts, goa: h.goa.WithAttrs(attrs)}
+	if h.json != nil {
+		handler.json = h.json.WithAttrs(attrs)
 	}
+	return handler
 }
 
 func (h *Handler) WithGroup(name string) slog.Handler {
 	if len(name) == 0 {
 		return h
 	}
-	if h.goa == nil {
-		h.goa = new(withsupport.GroupOrAttrs)
+	handler := &Handler{t: h.t, opts: h.opts, goa: h.goa.WithGroup(name)}
+	if h.json != nil {
+		handler.json = h.json.WithGroup(name)
+	}
+	return handler
+}
+
+// loggerWriter logs every write, which slog.JSONHandler issues once per record, as a line.
+type loggerWriter struct {
+	t Logger
+}
+
+func (w loggerWriter) Write(p []byte) (int, error) {
+	w.t.Log(string(bytes.TrimSuffix(p, []byte("\n"))))
+	return len(p), nil
+}
+
+// replaceJSONAttr drops the time of records and shortens their source to file:line.
+func replaceJSONAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
 	}
-	return &Handler{
-		t:   h.t,
-		goa: h.goa.WithGroup(name),
+	switch a.Key {
+	case slog.TimeKey:
+		return slog.Attr{}
+	case slog.SourceKey:
+		if src, ok := a.Value.Any().(*slog.Source); ok {
+			return slog.String(slog.SourceKey, fmt.Sprintf("%s:%d", filepath.Base(src.File), src.Line))
+		}
 	}
+	return a
 }
+
+// source returns the file:line of pc.
+func source(pc uintptr) string {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+}
+
 func (h *Handler) buildAttrs(record slog.Record) string {
 	var (
 		builder strings.Builder
-		counter int
+		prefix  string
 	)
 	groups := h.goa.Apply(func(groups []string, a slog.Attr) {
-		if counter != 0 {
-			builder.WriteString(" ")
-		}
-		if len(groups) == 0 {
-			builder.WriteString(fmt.Sprintf("%s=%s", a.Key, a.Value.String()))
-		} else {
-			builder.WriteString(fmt.Sprintf("%s.%s=%s", strings.Join(groups, "."), a.Key, a.Value.String()))
+		if len(groups) > 0 {
+			prefix = strings.Join(groups, ".") + "."
 		}
-		counter++
+		writeAttr(&builder, prefix, a)
 	})
+	prefix = ""
+	if len(groups) > 0 {
+		prefix = strings.Join(groups, ".") + "."
+	}
 	record.Attrs(func(a slog.Attr) bool {
-		if counter != 0 {
-			builder.WriteString(" ")
-		}
-		if len(groups) == 0 {
-			builder.WriteString(fmt.Sprintf("%s=%s", a.Key, a.Value.String()))
-		} else {
-			builder.WriteString(fmt.Sprintf("%s.%s=%s", strings.Join(groups, "."), a.Key, a.Value.String()))
-		}
-		counter++
+		writeAttr(&builder, prefix, a)
 		return true
 	})
 	return builder.String()
 }
+
+// writeAttr writes a as "prefix.key=value", separated by a space from the attrs written before.
+// Values are resolved, groups are written as "key=[k=v k=v]", and empty attrs and groups are skipped.
+func writeAttr(builder *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		if a.Key == "" && a.Value.Kind() == slog.KindAny && a.Value.Any() == nil {
+			return
+		}
+		if builder.Len() > 0 {
+			builder.WriteString(" ")
+		}
+		builder.WriteString(fmt.Sprintf("%s%s=%s", prefix, a.Key, a.Value.String()))
+		return
+	}
+
+	var group strings.Builder
+	for _, ga := range a.Value.Group() {
+		writeAttr(&group, "", ga)
+	}
+	if group.Len() == 0 {
+		return
+	}
+	if builder.Len() > 0 {
+		builder.WriteString(" ")
+	}
+	if a.Key == "" {
+		builder.WriteString(group.String())
+		return
+	}
+	builder.WriteString(fmt.Sprintf("%s%s=[%s]", prefix, a.Key, group.String()))
+}
@@ -1,10 +1,16 @@
 package xtesting
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/galecore/xslog/util"
+	"github.com/galecore/xslog/xslogtest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/exp/slog"
 )
 
@@ -84,3 +90,132 @@ func TestHandler_WithGroup(t *testing.T) {
 	}
 	assert.Equal(t, "DEBUG: test [group.key=value group.int=1]INFO: test [group.key=value group.int=1]WARN: test [group.key=value group.int=1]ERROR: test [group.key=value group.int=1]", logger.B.String())
 }
+
+// lineLogger collects the lines logged by a Handler. It is safe for concurrent use.
+type lineLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *lineLogger) Log(args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprint(args...))
+}
+
+func (l *lineLogger) Logf(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+// parseLine decodes a line of the form "LEVEL: msg [k=v g=[k=v]]" for the conformance suite.
+// Values must not contain spaces or brackets.
+func parseLine(t *testing.T, line string) map[string]any {
+	level, rest, ok := strings.Cut(line, ": ")
+	require.True(t, ok, line)
+	msg, attrs, ok := strings.Cut(rest, " [")
+	require.True(t, ok, line)
+	record, rest := parseAttrs(t, attrs)
+	require.Empty(t, rest, line)
+	record[slog.LevelKey] = level
+	record[slog.MessageKey] = msg
+	record[slog.TimeKey] = "" // Handler never prints times
+	return record
+}
+
+// parseAttrs decodes attrs up to the closing bracket of their group and returns the remaining input.
+func parseAttrs(t *testing.T, s string) (map[string]any, string) {
+	attrs := make(map[string]any)
+	for {
+		s = strings.TrimPrefix(s, " ")
+		if strings.HasPrefix(s, "]") {
+			return attrs, s[1:]
+		}
+		key, rest, ok := strings.Cut(s, "=")
+		require.True(t, ok, s)
+		// Keys of attrs in groups opened with WithGroup are prefixed with the group names.
+		path := strings.Split(key, ".")
+		group := attrs
+		for _, name := range path[:len(path)-1] {
+			if _, ok := group[name]; !ok {
+				group[name] = make(map[string]any)
+			}
+			group = group[name].(map[string]any)
+		}
+		key = path[len(path)-1]
+		if strings.HasPrefix(rest, "[") {
+			group[key], s = parseAttrs(t, rest[1:])
+			continue
+		}
+		end := strings.IndexAny(rest, " ]")
+		require.GreaterOrEqual(t, end, 0, rest)
+		group[key], s = rest[:end], rest[end:]
+	}
+}
+
+func TestHandler_Conformance(t *testing.T) {
+	xslogtest.Run(t, func(t *testing.T) (slog.Handler, func() []map[string]any) {
+		l := &lineLogger{}
+		return NewHandler(l), func() []map[string]any {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			records := make([]map[string]any, len(l.lines))
+			for i, line := range l.lines {
+				records[i] = parseLine(t, line)
+			}
+			return records
+		}
+	}, &xslogtest.Options{
+		// Handler never prints times, so the time key is always added by the results function.
+		Skip: []string{"zero-time"},
+	})
+}
+
+func TestHandlerWithOptions(t *testing.T) {
+	t.Run("level", func(t *testing.T) {
+		logger := util.NewBufferedLogger()
+		slog.New(NewHandlerWithOptions(logger, &HandlerOptions{Level: slog.LevelWarn})).Info("skipped")
+		slog.New(NewHandlerWithOptions(logger, &HandlerOptions{Level: slog.LevelWarn})).Warn("printed")
+		assert.Equal(t, "WARN: printed []", logger.B.String())
+	})
+
+	t.Run("source", func(t *testing.T) {
+		logger := util.NewBufferedLogger()
+		slog.New(NewHandlerWithOptions(logger, &HandlerOptions{AddSource: true})).Info("test", "k", "v")
+		assert.Regexp(t, `^handler_test\.go:\d+: INFO: test \[k=v\]$`, logger.B.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		l := &lineLogger{}
+		h := NewHandlerWithOptions(l, &HandlerOptions{Format: FormatJSON, AddSource: true})
+		slog.New(h).With("k", "v").WithGroup("g").Info("test", "n", 1, "lv", testLogValuer{})
+		require.Len(t, l.lines, 1)
+		assert.Regexp(t, `^\{"level":"INFO","source":"handler_test\.go:\d+","msg":"test","k":"v","g":\{"n":1,"lv":\{"id":42\}\}\}$`, l.lines[0])
+	})
+}
+
+type testLogValuer struct{}
+
+func (testLogValuer) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("id", 42))
+}
+
+func TestHandlerWithOptions_Conformance(t *testing.T) {
+	xslogtest.Run(t, func(t *testing.T) (slog.Handler, func() []map[string]any) {
+		l := &lineLogger{}
+		return NewHandlerWithOptions(l, &HandlerOptions{Format: FormatJSON}), func() []map[string]any {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			records := make([]map[string]any, len(l.lines))
+			for i, line := range l.lines {
+				require.NoError(t, json.Unmarshal([]byte(line), &records[i]))
+				records[i][slog.TimeKey] = "" // Handler never prints times
+			}
+			return records
+		}
+	}, &xslogtest.Options{
+		// slog.JSONHandler opens groups that end up empty, and Handler never prints times.
+		Skip: []string{"empty-with-group", "empty-nested-with-group", "zero-time"},
+	})
+}
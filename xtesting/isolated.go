@@ -0,0 +1,122 @@
+package xtesting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"golang.org/x/exp/slog"
+)
+
+// ErrTestFinished is returned by IsolatedHandler.Handle for records logged after the test has finished.
+var ErrTestFinished = errors.New("xtesting: record logged after the test has finished")
+
+// IsolatedOptions configures NewIsolatedHandler.
+type IsolatedOptions struct {
+	// Verbose also prints the records of tests that passed when tests run with -v.
+	Verbose bool
+	// Handler configures how records are printed.
+	Handler HandlerOptions
+}
+
+// IsolatedHandler is a Handler that buffers the records of a single test and prints them
+// only once the test has finished, so the output of parallel tests does not interleave.
+// Handlers derived with WithAttrs and WithGroup share the buffer of the handler they were derived from.
+type IsolatedHandler struct {
+	h   *Handler
+	log *isolatedLog
+}
+
+// NewIsolatedHandler returns a handler whose records are printed with t.Log from a t.Cleanup function
+// if t failed, or with -v if opts.Verbose is set, and are dropped otherwise.
+// Records logged after t has finished are not printed; Handle reports them on stderr and returns ErrTestFinished
+// instead of panicking like t.Log.
+func NewIsolatedHandler(t testing.TB, opts *IsolatedOptions) *IsolatedHandler {
+	var o IsolatedOptions
+	if opts != nil {
+		o = *opts
+	}
+	log := &isolatedLog{name: t.Name()}
+	t.Cleanup(func() {
+		lines := log.finish()
+		if t.Failed() || (o.Verbose && testing.Verbose()) {
+			for _, line := range lines {
+				t.Log(line)
+			}
+		}
+	})
+	return &IsolatedHandler{h: NewHandlerWithOptions(log, &o.Handler), log: log}
+}
+
+func (h *IsolatedHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.h.Enabled(ctx, level)
+}
+
+func (h *IsolatedHandler) Handle(ctx context.Context, record slog.Record) error {
+	if h.log.finished() {
+		err := fmt.Errorf("%w: %s logged %q", ErrTestFinished, h.log.name, record.Message)
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	return h.h.Handle(ctx, record)
+}
+
+func (h *IsolatedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return &IsolatedHandler{h: h.h.WithAttrs(attrs).(*Handler), log: h.log}
+}
+
+func (h *IsolatedHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &IsolatedHandler{h: h.h.WithGroup(name).(*Handler), log: h.log}
+}
+
+// isolatedLog is a Logger that buffers lines until the test has finished.
+type isolatedLog struct {
+	name  string
+	mu    sync.Mutex
+	lines []string
+	done  bool
+}
+
+func (l *isolatedLog) Log(args ...any) {
+	l.add(fmt.Sprint(args...))
+}
+
+func (l *isolatedLog) Logf(format string, args ...any) {
+	l.add(fmt.Sprintf(format, args...))
+}
+
+func (l *isolatedLog) add(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done {
+		// The test finished between the check in Handle and now.
+		fmt.Fprintf(os.Stderr, "%v: %s logged %q\n", ErrTestFinished, l.name, line)
+		return
+	}
+	l.lines = append(l.lines, line)
+}
+
+func (l *isolatedLog) finished() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.done
+}
+
+// finish marks the test as finished and returns the buffered lines.
+func (l *isolatedLog) finish() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.done = true
+	lines := l.lines
+	l.lines = nil
+	return lines
+}
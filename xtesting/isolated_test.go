@@ -0,0 +1,76 @@
+package xtesting
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+)
+
+// fakeTB records the cleanups and output of a test. Methods it does not override panic.
+type fakeTB struct {
+	testing.TB
+	failed   bool
+	cleanups []func()
+	logs     []string
+}
+
+func (t *fakeTB) Name() string     { return "TestFake" }
+func (t *fakeTB) Failed() bool     { return t.failed }
+func (t *fakeTB) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+func (t *fakeTB) Log(args ...any)  { t.logs = append(t.logs, fmt.Sprint(args...)) }
+func (t *fakeTB) finish(failed bool) {
+	t.failed = failed
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+func TestIsolatedHandler(t *testing.T) {
+	t.Run("passed", func(t *testing.T) {
+		tb := &fakeTB{}
+		logger := slog.New(NewIsolatedHandler(tb, nil))
+		logger.Info("hello", "k", "v")
+		tb.finish(false)
+		assert.Empty(t, tb.logs)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		tb := &fakeTB{}
+		logger := slog.New(NewIsolatedHandler(tb, nil)).With("service", "api").WithGroup("http")
+		logger.Info("request", "status", 200)
+		logger.Error("failure")
+		tb.finish(true)
+		assert.Equal(t, []string{
+			"INFO: request [service=api http.status=200]",
+			"ERROR: failure [service=api]",
+		}, tb.logs)
+	})
+
+	t.Run("after finish", func(t *testing.T) {
+		tb := &fakeTB{}
+		h := NewIsolatedHandler(tb, nil)
+		tb.finish(true)
+		err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "late", 0))
+		require.ErrorIs(t, err, ErrTestFinished)
+		assert.Contains(t, err.Error(), `TestFake logged "late"`)
+		assert.Empty(t, tb.logs)
+	})
+}
+
+func TestIsolatedHandler_Parallel(t *testing.T) {
+	for i := 0; i < 4; i++ {
+		i := i
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+			logger := slog.New(NewIsolatedHandler(t, &IsolatedOptions{Verbose: true}))
+			for j := 0; j < 10; j++ {
+				logger.Info("step", "test", i, "step", j)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package xtesting
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slog"
+)
+
+// Matcher selects records captured by a Recorder.
+type Matcher struct {
+	desc  string
+	match func(Record) bool
+}
+
+// String describes the matcher in failure messages.
+func (m Matcher) String() string {
+	return m.desc
+}
+
+// Match reports whether record satisfies m.
+func (m Matcher) Match(record Record) bool {
+	return m.match(record)
+}
+
+// WithLevel matches records logged at level.
+func WithLevel(level slog.Level) Matcher {
+	return Matcher{
+		desc:  "level=" + level.String(),
+		match: func(r Record) bool { return r.Level == level },
+	}
+}
+
+// MinLevel matches records logged at level or above.
+func MinLevel(level slog.Level) Matcher {
+	return Matcher{
+		desc:  "level>=" + level.String(),
+		match: func(r Record) bool { return r.Level >= level },
+	}
+}
+
+// MessageMatches matches records whose message matches the regular expression pattern.
+// It panics if pattern does not compile.
+func MessageMatches(pattern string) Matcher {
+	re := regexp.MustCompile(pattern)
+	return Matcher{
+		desc:  fmt.Sprintf("message=~%q", pattern),
+		match: func(r Record) bool { return re.MatchString(r.Message) },
+	}
+}
+
+// HasAttr matches records that have an attr at path. See Record.Attr.
+func HasAttr(path string) Matcher {
+	return Matcher{
+		desc: "has " + path,
+		match: func(r Record) bool {
+			_, ok := r.Attr(path)
+			return ok
+		},
+	}
+}
+
+// AttrEqual matches records whose attr at path equals value. Value may be a slog.Value or any Go value,
+// which is converted with slog.AnyValue, so AttrEqual("status", 200) matches slog.Int("status", 200).
+func AttrEqual(path string, value any) Matcher {
+	want, ok := value.(slog.Value)
+	if !ok {
+		want = slog.AnyValue(value)
+	}
+	want = want.Resolve()
+	return Matcher{
+		desc: fmt.Sprintf("%s=%s", path, want),
+		match: func(r Record) bool {
+			got, ok := r.Attr(path)
+			return ok && valuesEqual(got, want)
+		},
+	}
+}
+
+// InGroup matches records that have a group at path whose attrs satisfy all matchers.
+// Paths of the matchers are relative to the group.
+func InGroup(path string, matchers ...Matcher) Matcher {
+	descs := make([]string, len(matchers))
+	for i, m := range matchers {
+		descs[i] = m.desc
+	}
+	return Matcher{
+		desc: fmt.Sprintf("%s{%s}", path, strings.Join(descs, " ")),
+		match: func(r Record) bool {
+			group, ok := r.Attr(path)
+			if !ok || group.Kind() != slog.KindGroup {
+				return false
+			}
+			r.Attrs = group.Group()
+			return matchAll(r, matchers)
+		},
+	}
+}
+
+func matchAll(record Record, matchers []Matcher) bool {
+	for _, m := range matchers {
+		if !m.match(record) {
+			return false
+		}
+	}
+	return true
+}
+
+func valuesEqual(a, b slog.Value) bool {
+	if a.Kind() == slog.KindAny && b.Kind() == slog.KindAny {
+		return reflect.DeepEqual(a.Any(), b.Any())
+	}
+	if a.Kind() == slog.KindGroup && b.Kind() == slog.KindGroup {
+		ga, gb := a.Group(), b.Group()
+		if len(ga) != len(gb) {
+			return false
+		}
+		for i := range ga {
+			if ga[i].Key != gb[i].Key || !valuesEqual(ga[i].Value, gb[i].Value) {
+				return false
+			}
+		}
+		return true
+	}
+	return a.Kind() == b.Kind() && a.Equal(b)
+}
@@ -0,0 +1,235 @@
+package xtesting
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jba/slog/withsupport"
+	"golang.org/x/exp/slog"
+)
+
+// Record is a structured copy of a record handled by a Recorder.
+type Record struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	PC      uintptr
+	// Attrs holds the attrs added with WithAttrs followed by the attrs of the record, nested in the groups
+	// opened with WithGroup. Values are resolved, and groups are represented as slog.KindGroup values.
+	Attrs []slog.Attr
+}
+
+// Attr returns the value of the attr at path, where path lists the keys of the enclosing groups
+// and the key of the attr separated by dots, e.g. "http.status". Keys that contain dots themselves,
+// like "grpc.code", are found as well.
+func (r Record) Attr(path string) (slog.Value, bool) {
+	return lookupAttr(r.Attrs, strings.Split(path, "."))
+}
+
+// String formats r like Handler, e.g. "INFO: msg [k=v g.k=v]".
+func (r Record) String() string {
+	var b strings.Builder
+	b.WriteString(r.Level.String())
+	b.WriteString(": ")
+	b.WriteString(r.Message)
+	b.WriteString(" [")
+	writeAttrs(&b, "", r.Attrs, true)
+	b.WriteString("]")
+	return b.String()
+}
+
+// Recorder is a slog.Handler that stores structured copies of all records, so tests can query
+// and assert on what was logged. Handlers derived with WithAttrs and WithGroup share the records
+// of the Recorder they were derived from. It is safe for concurrent use.
+type Recorder struct {
+	state *recorderState
+	goa   *withsupport.GroupOrAttrs
+}
+
+type recorderState struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{state: new(recorderState)}
+}
+
+func (r *Recorder) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (r *Recorder) Handle(_ context.Context, record slog.Record) error {
+	rec := Record{
+		Time:    record.Time,
+		Level:   record.Level,
+		Message: record.Message,
+		PC:      record.PC,
+		Attrs:   nestAttrs(r.goa, record),
+	}
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	r.state.records = append(r.state.records, rec)
+	return nil
+}
+
+func (r *Recorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return r
+	}
+	return &Recorder{state: r.state, goa: r.goa.WithAttrs(attrs)}
+}
+
+func (r *Recorder) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return r
+	}
+	return &Recorder{state: r.state, goa: r.goa.WithGroup(name)}
+}
+
+// Records returns a copy of the records handled so far, in the order they were handled.
+func (r *Recorder) Records() []Record {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	return append([]Record(nil), r.state.records...)
+}
+
+// Reset drops all records handled so far.
+func (r *Recorder) Reset() {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	r.state.records = nil
+}
+
+// Find returns the records that satisfy all matchers.
+func (r *Recorder) Find(matchers ...Matcher) []Record {
+	var found []Record
+	for _, record := range r.Records() {
+		if matchAll(record, matchers) {
+			found = append(found, record)
+		}
+	}
+	return found
+}
+
+// Count returns the number of records that satisfy all matchers.
+func (r *Recorder) Count(matchers ...Matcher) int {
+	return len(r.Find(matchers...))
+}
+
+// Results returns the records handled so far as maps in the form expected by xslogtest.NewHandlerFunc:
+// attrs and the built-in keys map to their values formatted as strings, groups map to nested maps,
+// and zero times are omitted.
+func (r *Recorder) Results() []map[string]any {
+	records := r.Records()
+	results := make([]map[string]any, len(records))
+	for i, record := range records {
+		m := attrsMap(record.Attrs)
+		m[slog.LevelKey] = record.Level.String()
+		m[slog.MessageKey] = record.Message
+		if !record.Time.IsZero() {
+			m[slog.TimeKey] = record.Time.String()
+		}
+		results[i] = m
+	}
+	return results
+}
+
+func attrsMap(attrs []slog.Attr) map[string]any {
+	m := make(map[string]any, len(attrs))
+	for _, a := range attrs {
+		if a.Value.Kind() == slog.KindGroup {
+			m[a.Key] = attrsMap(a.Value.Group())
+		} else {
+			m[a.Key] = a.Value.String()
+		}
+	}
+	return m
+}
+
+// nestAttrs returns the attrs of goa and record nested in the groups of goa.
+func nestAttrs(goa *withsupport.GroupOrAttrs, record slog.Record) []slog.Attr {
+	// levels[i] holds the attrs of the i-th open group, with levels[0] being the top level.
+	levels := [][]slog.Attr{nil}
+	var groups []string
+	for _, g := range goa.Collect() {
+		if g.Group != "" {
+			groups = append(groups, g.Group)
+			levels = append(levels, nil)
+			continue
+		}
+		last := len(levels) - 1
+		levels[last] = appendResolved(levels[last], g.Attrs...)
+	}
+	last := len(levels) - 1
+	record.Attrs(func(a slog.Attr) bool {
+		levels[last] = appendResolved(levels[last], a)
+		return true
+	})
+	for i := last; i > 0; i-- {
+		if len(levels[i]) > 0 {
+			levels[i-1] = append(levels[i-1], slog.Attr{Key: groups[i-1], Value: slog.GroupValue(levels[i]...)})
+		}
+	}
+	return levels[0]
+}
+
+// appendResolved appends attrs to dst with their values resolved, following the rules of slog.Handler:
+// empty attrs and empty groups are dropped and groups with empty keys are inlined.
+func appendResolved(dst []slog.Attr, attrs ...slog.Attr) []slog.Attr {
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() != slog.KindGroup {
+			if a.Key != "" || a.Value.Kind() != slog.KindAny || a.Value.Any() != nil {
+				dst = append(dst, a)
+			}
+			continue
+		}
+		group := appendResolved(nil, a.Value.Group()...)
+		switch {
+		case len(group) == 0:
+		case a.Key == "":
+			dst = append(dst, group...)
+		default:
+			dst = append(dst, slog.Attr{Key: a.Key, Value: slog.GroupValue(group...)})
+		}
+	}
+	return dst
+}
+
+func lookupAttr(attrs []slog.Attr, path []string) (slog.Value, bool) {
+	for _, a := range attrs {
+		for n := 1; n <= len(path); n++ {
+			if a.Key != strings.Join(path[:n], ".") {
+				continue
+			}
+			if n == len(path) {
+				return a.Value, true
+			}
+			if a.Value.Kind() == slog.KindGroup {
+				if v, ok := lookupAttr(a.Value.Group(), path[n:]); ok {
+					return v, true
+				}
+			}
+		}
+	}
+	return slog.Value{}, false
+}
+
+func writeAttrs(b *strings.Builder, prefix string, attrs []slog.Attr, first bool) bool {
+	for _, a := range attrs {
+		if a.Value.Kind() == slog.KindGroup {
+			first = writeAttrs(b, prefix+a.Key+".", a.Value.Group(), first)
+			continue
+		}
+		if !first {
+			b.WriteString(" ")
+		}
+		fmt.Fprintf(b, "%s%s=%s", prefix, a.Key, a.Value.String())
+		first = false
+	}
+	return first
+}
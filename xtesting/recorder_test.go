@@ -0,0 +1,155 @@
+package xtesting
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/galecore/xslog/xslogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	logger := slog.New(r).With("service", "api").WithGroup("http").With("method", "GET").WithGroup("empty")
+	logger.Info("request", "status", 200, slog.Group("", slog.String("inlined", "yes")), slog.Group("none"))
+	slog.New(r).Warn("retry", slog.Group("db", slog.Int("attempt", 2)))
+
+	records := r.Records()
+	require.Len(t, records, 2)
+	assert.Equal(t, []slog.Attr{
+		slog.String("service", "api"),
+		slog.Group("http",
+			slog.String("method", "GET"),
+			slog.Group("empty", slog.Int("status", 200), slog.String("inlined", "yes")),
+		),
+	}, records[0].Attrs)
+	assert.Equal(t, "INFO: request [service=api http.method=GET http.empty.status=200 http.empty.inlined=yes]", records[0].String())
+
+	status, ok := records[0].Attr("http.empty.status")
+	assert.True(t, ok)
+	assert.Equal(t, int64(200), status.Int64())
+	_, ok = records[0].Attr("http.status")
+	assert.False(t, ok)
+
+	slog.New(r).Info("call", slog.String("grpc.code", "OK"), slog.Group("peer", slog.String("net.addr", "bufconn")))
+	code, ok := r.Records()[2].Attr("grpc.code")
+	assert.True(t, ok)
+	assert.Equal(t, "OK", code.String())
+	addr, ok := r.Records()[2].Attr("peer.net.addr")
+	assert.True(t, ok)
+	assert.Equal(t, "bufconn", addr.String())
+
+	r.Reset()
+	assert.Empty(t, r.Records())
+}
+
+func TestRecorder_Find(t *testing.T) {
+	r := NewRecorder()
+	logger := slog.New(r)
+	logger.Info("request served", slog.Group("http", slog.Int("status", 200), slog.String("path", "/users")))
+	logger.Error("request failed", slog.Group("http", slog.Int("status", 500)), slog.Any("tags", []string{"a"}))
+	logger.Debug("cache miss", "key", "user:1")
+
+	assert.Equal(t, 2, r.Count(MessageMatches("^request ")))
+	assert.Equal(t, 1, r.Count(WithLevel(slog.LevelDebug)))
+	assert.Equal(t, 2, r.Count(MinLevel(slog.LevelInfo)))
+	assert.Equal(t, 1, r.Count(AttrEqual("http.status", 500)))
+	assert.Equal(t, 1, r.Count(AttrEqual("http.status", slog.Int64Value(200))))
+	assert.Equal(t, 1, r.Count(AttrEqual("tags", []string{"a"})))
+	assert.Equal(t, 1, r.Count(HasAttr("http.path")))
+	assert.Equal(t, 2, r.Count(InGroup("http", HasAttr("status"))))
+	assert.Equal(t, 1, r.Count(InGroup("http", AttrEqual("status", 200), AttrEqual("path", "/users"))))
+	assert.Equal(t, 0, r.Count(InGroup("key")))
+
+	found := r.Find(MinLevel(slog.LevelWarn))
+	require.Len(t, found, 1)
+	assert.Equal(t, "request failed", found[0].Message)
+}
+
+func TestRecorder_Concurrent(t *testing.T) {
+	r := NewRecorder()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			logger := slog.New(r).With("worker", i)
+			for j := 0; j < 10; j++ {
+				logger.InfoCtx(context.Background(), "tick")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 100, r.Count(MessageMatches("tick")))
+	assert.Equal(t, 10, r.Count(AttrEqual("worker", 3)))
+}
+
+// fakeT records the failures of the Require functions.
+type fakeT struct {
+	errors []string
+	failed bool
+}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) FailNow() {
+	t.failed = true
+}
+
+func TestRequire(t *testing.T) {
+	r := NewRecorder()
+	logger := slog.New(r)
+	logger.Info("request", "status", 200)
+	logger.Error("request", "status", 500)
+
+	t.Run("pass", func(t *testing.T) {
+		ft := &fakeT{}
+		RequireLogged(ft, r, MessageMatches("request"), AttrEqual("status", 500))
+		RequireNotLogged(ft, r, WithLevel(slog.LevelWarn))
+		RequireCount(ft, r, 2, MessageMatches("request"))
+		assert.False(t, ft.failed)
+		assert.Empty(t, ft.errors)
+	})
+
+	t.Run("logged", func(t *testing.T) {
+		ft := &fakeT{}
+		RequireLogged(ft, r, WithLevel(slog.LevelError), AttrEqual("status", 200))
+		assert.True(t, ft.failed)
+		assert.Equal(t, []string{`no record matches [level=ERROR status=200]
+captured records:
+  - INFO: request [status=200]
+      mismatched [level=ERROR]
+  - ERROR: request [status=500]
+      mismatched [status=200]`}, ft.errors)
+	})
+
+	t.Run("not logged", func(t *testing.T) {
+		ft := &fakeT{}
+		RequireNotLogged(ft, r, MinLevel(slog.LevelError))
+		assert.True(t, ft.failed)
+		assert.Equal(t, []string{`unexpected record matches [level>=ERROR]
+captured records:
+  + ERROR: request [status=500]`}, ft.errors)
+	})
+
+	t.Run("count", func(t *testing.T) {
+		ft := &fakeT{}
+		RequireCount(ft, NewRecorder(), 1, HasAttr("status"))
+		assert.True(t, ft.failed)
+		assert.Equal(t, []string{"0 records match [has status], want 1\ncaptured records: none"}, ft.errors)
+	})
+}
+
+func TestRecorder_Conformance(t *testing.T) {
+	xslogtest.Run(t, func(t *testing.T) (slog.Handler, func() []map[string]any) {
+		r := NewRecorder()
+		return r, r.Results
+	}, nil)
+}
@@ -0,0 +1,82 @@
+package xtesting
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TestingT is the subset of testing.TB used by the Require functions. It is implemented by *testing.T
+// and compatible with require.TestingT.
+type TestingT interface {
+	Errorf(format string, args ...any)
+	FailNow()
+}
+
+type tHelper interface {
+	Helper()
+}
+
+// RequireLogged fails the test immediately unless r captured a record that satisfies all matchers.
+func RequireLogged(t TestingT, r *Recorder, matchers ...Matcher) {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	if r.Count(matchers...) == 0 {
+		t.Errorf("no record matches %s\n%s", describe(matchers), diff(r.Records(), matchers))
+		t.FailNow()
+	}
+}
+
+// RequireNotLogged fails the test immediately if r captured a record that satisfies all matchers.
+func RequireNotLogged(t TestingT, r *Recorder, matchers ...Matcher) {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	if found := r.Find(matchers...); len(found) > 0 {
+		t.Errorf("unexpected record matches %s\n%s", describe(matchers), diff(found, nil))
+		t.FailNow()
+	}
+}
+
+// RequireCount fails the test immediately unless r captured exactly n records that satisfy all matchers.
+func RequireCount(t TestingT, r *Recorder, n int, matchers ...Matcher) {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	if count := r.Count(matchers...); count != n {
+		t.Errorf("%d records match %s, want %d\n%s", count, describe(matchers), n, diff(r.Records(), matchers))
+		t.FailNow()
+	}
+}
+
+func describe(matchers []Matcher) string {
+	descs := make([]string, len(matchers))
+	for i, m := range matchers {
+		descs[i] = m.desc
+	}
+	return "[" + strings.Join(descs, " ") + "]"
+}
+
+// diff lists records, marking the ones that satisfy all matchers with "+"
+// and naming the matchers that the others fail.
+func diff(records []Record, matchers []Matcher) string {
+	if len(records) == 0 {
+		return "captured records: none"
+	}
+	var b strings.Builder
+	b.WriteString("captured records:")
+	for _, record := range records {
+		var failed []Matcher
+		for _, m := range matchers {
+			if !m.match(record) {
+				failed = append(failed, m)
+			}
+		}
+		if len(failed) == 0 {
+			fmt.Fprintf(&b, "\n  + %s", record)
+		} else {
+			fmt.Fprintf(&b, "\n  - %s\n      mismatched %s", record, describe(failed))
+		}
+	}
+	return b.String()
+}
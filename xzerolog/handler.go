@@ -1,64 +1,315 @@
 package xzerolog
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"sync"
 
-	"github.com/jba/slog/withsupport"
+	"github.com/galecore/xslog/xdata"
 	"github.com/rs/zerolog"
 	"golang.org/x/exp/slog"
 )
 
+// HandlerOptions configures NewHandlerWithOptions.
+type HandlerOptions struct {
+	// Clock, if set, provides the time of records without one, e.g. to make output reproducible in tests.
+	// Without it, such records get the time of the clock bound to the context with xdata.WithClock, if any.
+	// Records with a time, such as those of xslog.Log, which reads the bound clock itself, keep it.
+	Clock xdata.Clock
+	// Levels overrides the zerolog levels of individual slog levels. Other levels are mapped with ZerologLevel.
+	Levels map[slog.Level]zerolog.Level
+	// AddSlogLevel also writes the numeric slog level of records under SlogLevelKey.
+	AddSlogLevel bool
+	// SlogLevelKey is the key of the slog level written with AddSlogLevel. Defaults to DefaultSlogLevelKey.
+	SlogLevelKey string
+}
+
+// DefaultSlogLevelKey is the default key of the slog level written with HandlerOptions.AddSlogLevel.
+const DefaultSlogLevelKey = "slog_level"
+
+// ZerologLevel maps ranges of slog levels to zerolog levels: levels below slog.LevelDebug map to trace,
+// levels from slog.LevelDebug, slog.LevelInfo, slog.LevelWarn and slog.LevelError up to the next of them
+// map to debug, info, warn and error, levels from slog.LevelError+4 to fatal and levels from slog.LevelError+8
+// to panic. Handler writes fatal and panic records without exiting or panicking.
+func ZerologLevel(level slog.Level) zerolog.Level {
+	switch {
+	case level < slog.LevelDebug:
+		return zerolog.TraceLevel
+	case level < slog.LevelInfo:
+		return zerolog.DebugLevel
+	case level < slog.LevelWarn:
+		return zerolog.InfoLevel
+	case level < slog.LevelError:
+		return zerolog.WarnLevel
+	case level < slog.LevelError+4:
+		return zerolog.ErrorLevel
+	case level < slog.LevelError+8:
+		return zerolog.FatalLevel
+	default:
+		return zerolog.PanicLevel
+	}
+}
+
+// Handler writes records with a zerolog.Logger, nesting attrs in groups like slog.JSONHandler.
+// Attrs added with WithAttrs are rendered once: outside of groups into the context of the logger,
+// and within groups into the JSON members of their group, which Handle copies into every record.
 type Handler struct {
-	l *zerolog.Logger
+	l    *zerolog.Logger
+	opts HandlerOptions
 
-	goa *withsupport.GroupOrAttrs
+	// groups lists the groups opened with WithGroup, outermost first.
+	groups []group
+	// prefix is the JSON of the outermost group up to the members of the record, which are
+	// followed by a closing brace per group. empty is the JSON of the outermost group for records
+	// without attrs, or nil if all groups are empty then.
+	prefix []byte
+	empty  []byte
+}
+
+type group struct {
+	name  string
+	key   []byte // the JSON encoded name of the group followed by a colon
+	attrs []byte // the JSON members of the attrs added to the group, separated by commas
 }
 
 func NewHandler(l *zerolog.Logger) *Handler {
 	return &Handler{l: l}
 }
 
-func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
-	return h.l.GetLevel() <= slogLevelToZerologLevel(level)
+func NewHandlerWithOptions(l *zerolog.Logger, opts *HandlerOptions) *Handler {
+	h := &Handler{l: l}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.SlogLevelKey == "" {
+		h.opts.SlogLevelKey = DefaultSlogLevelKey
+	}
+	return h
 }
 
-func (h *Handler) Handle(_ context.Context, record slog.Record) error {
-	event := h.l.WithLevel(slogLevelToZerologLevel(record.Level))
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.l.GetLevel() <= h.level(level)
+}
 
-	groups := h.goa.Apply(func(groups []string, a slog.Attr) {
-		if len(groups) == 0 {
-			event = addAttrToEvent(event, a)
-			return
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Time.IsZero() {
+		if h.opts.Clock != nil {
+			record.Time = h.opts.Clock.Now()
+		} else if clock, ok := xdata.BoundClock(ctx); ok {
+			record.Time = clock.Now()
 		}
-		childEvent := addAttrToEvent(zerolog.Dict(), a)
-		for i := len(groups) - 1; i >= 1; i-- {
-			childEvent = childEvent.Dict(groups[i], event)
-		}
-		event = event.Dict(groups[0], childEvent)
-	})
-	if len(groups) == 0 {
+	}
+	// Unlike Fatal and Panic, WithLevel never exits or panics.
+	event := h.l.WithLevel(h.level(record.Level))
+	if h.opts.AddSlogLevel {
+		event = event.Int(h.opts.SlogLevelKey, int(record.Level))
+	}
+	if len(h.groups) == 0 {
 		record.Attrs(func(attr slog.Attr) bool {
-			event = addAttrToEvent(event, attr)
+			event = appendAttr(event, attr)
 			return true
 		})
-		event.Time("time", record.Time).Msg(record.Message)
+	} else if record.NumAttrs() == 0 {
+		if h.empty != nil {
+			event = event.RawJSON(h.groups[0].name, h.empty)
+		}
+	} else {
+		buf := renderBuffers.Get().(*bytes.Buffer)
+		defer renderBuffers.Put(buf)
+		buf.Reset()
+		buf.Write(h.prefix)
+		ok := renderMembersTo(buf, func(event *zerolog.Event) *zerolog.Event {
+			record.Attrs(func(attr slog.Attr) bool {
+				event = appendAttr(event, attr)
+				return true
+			})
+			return event
+		})
+		if ok {
+			for range h.groups {
+				buf.WriteByte('}')
+			}
+			event = event.RawJSON(h.groups[0].name, buf.Bytes())
+		} else if h.empty != nil {
+			event = event.RawJSON(h.groups[0].name, h.empty)
+		}
+	}
+	event.Time("time", record.Time).Msg(record.Message)
+	return nil
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	attrs = resolveAttrs(attrs)
+	if len(attrs) == 0 {
+		return h
+	}
+	if len(h.groups) == 0 {
+		childLogger := h.l.With()
+		for _, attr := range attrs {
+			if value := renderValue(attr.Value); len(value) > 0 {
+				childLogger = childLogger.RawJSON(attr.Key, value)
+			}
+		}
+		l := childLogger.Logger()
+		return &Handler{l: &l, opts: h.opts}
+	}
+
+	groups := append([]group(nil), h.groups...)
+	last := &groups[len(groups)-1]
+	last.attrs = joinMembers(last.attrs, renderMembers(func(event *zerolog.Event) *zerolog.Event {
+		for _, attr := range attrs {
+			event = appendAttr(event, attr)
+		}
+		return event
+	}))
+	return newGroupHandler(h, groups)
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	key, _ := json.Marshal(name) // strings always marshal
+	groups := append(h.groups[:len(h.groups):len(h.groups)], group{name: name, key: append(key, ':')})
+	return newGroupHandler(h, groups)
+}
+
+// newGroupHandler returns a copy of h with groups open, pre-rendering the JSON of the groups.
+func newGroupHandler(h *Handler, groups []group) *Handler {
+	handler := &Handler{l: h.l, opts: h.opts, groups: groups, prefix: []byte{'{'}}
+	for i, g := range groups {
+		if i > 0 {
+			handler.prefix = append(handler.prefix, g.key...)
+			handler.prefix = append(handler.prefix, '{')
+		}
+		if len(g.attrs) > 0 {
+			handler.prefix = append(handler.prefix, g.attrs...)
+			handler.prefix = append(handler.prefix, ',')
+		}
+	}
+	handler.empty = emptyGroupMembers(groups)
+	return handler
+}
+
+// emptyGroupMembers returns the JSON object of the outermost of groups without nested groups that are empty,
+// or nil if all groups are empty.
+func emptyGroupMembers(groups []group) []byte {
+	var members []byte
+	for i := len(groups) - 1; i > 0; i-- {
+		members = joinMembers(groups[i].attrs, members)
+		if len(members) > 0 {
+			nested := make([]byte, 0, len(groups[i].key)+len(members)+2)
+			nested = append(nested, groups[i].key...)
+			nested = append(nested, '{')
+			nested = append(nested, members...)
+			members = append(nested, '}')
+		}
+	}
+	members = joinMembers(groups[0].attrs, members)
+	if len(members) == 0 {
 		return nil
 	}
+	return append(append([]byte{'{'}, members...), '}')
+}
 
-	childEvent := zerolog.Dict()
-	for i := 1; i < len(groups); i++ {
-		childEvent = childEvent.Dict(groups[i], childEvent)
+// joinMembers returns the JSON members a followed by the JSON members b.
+func joinMembers(a, b []byte) []byte {
+	switch {
+	case len(a) == 0:
+		return b
+	case len(b) == 0:
+		return a
 	}
-	record.Attrs(func(attr slog.Attr) bool {
-		childEvent = addAttrToEvent(childEvent, attr)
-		return true
+	joined := make([]byte, 0, len(a)+1+len(b))
+	joined = append(joined, a...)
+	joined = append(joined, ',')
+	return append(joined, b...)
+}
+
+var renderBuffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// renderMembers returns the JSON members of the fields that add appends to an event.
+func renderMembers(add func(event *zerolog.Event) *zerolog.Event) []byte {
+	var buf bytes.Buffer
+	renderMembersTo(&buf, add)
+	return buf.Bytes()
+}
+
+// renderMembersTo writes the JSON members of the fields that add appends to an event to dst,
+// and reports whether there were any.
+func renderMembersTo(dst *bytes.Buffer, add func(event *zerolog.Event) *zerolog.Event) bool {
+	buf := renderBuffers.Get().(*bytes.Buffer)
+	defer renderBuffers.Put(buf)
+	buf.Reset()
+	l := zerolog.New(buf)
+	add(l.Log()).Send()
+	// The event is written as an object followed by a newline.
+	members := bytes.TrimSuffix(bytes.TrimSpace(buf.Bytes()), []byte("}"))
+	members = bytes.TrimPrefix(members, []byte("{"))
+	dst.Write(members)
+	return len(members) > 0
+}
+
+// renderValue returns v encoded as a JSON value, or nil if v is an empty group.
+func renderValue(v slog.Value) []byte {
+	return renderField(func(event *zerolog.Event, key string) *zerolog.Event {
+		return appendAttr(event, slog.Attr{Key: key, Value: v})
 	})
-	event = event.Dict(groups[0], childEvent)
-	event.Time("time", record.Time).Msg(record.Message)
-	return nil
 }
 
-func addAttrToEvent(event *zerolog.Event, attr slog.Attr) *zerolog.Event {
+// renderField returns the JSON value of the field that add adds to an event under key, or nil if add adds nothing.
+func renderField(add func(event *zerolog.Event, key string) *zerolog.Event) []byte {
+	const key = "v"
+	members := renderMembers(func(event *zerolog.Event) *zerolog.Event {
+		return add(event, key)
+	})
+	if len(members) == 0 {
+		return nil
+	}
+	return bytes.TrimPrefix(members, []byte(`"`+key+`":`))
+}
+
+// resolveAttrs returns attrs with their values resolved, following the rules of slog.Handler:
+// empty attrs and empty groups are dropped and groups with empty keys are inlined.
+func resolveAttrs(attrs []slog.Attr) []slog.Attr {
+	resolved := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		attr.Value = attr.Value.Resolve()
+		if attr.Value.Kind() != slog.KindGroup {
+			if attr.Key != "" || attr.Value.Kind() != slog.KindAny || attr.Value.Any() != nil {
+				resolved = append(resolved, attr)
+			}
+			continue
+		}
+		members := resolveAttrs(attr.Value.Group())
+		switch {
+		case len(members) == 0:
+		case attr.Key == "":
+			resolved = append(resolved, members...)
+		default:
+			resolved = append(resolved, slog.Attr{Key: attr.Key, Value: slog.GroupValue(members...)})
+		}
+	}
+	return resolved
+}
+
+// appendAttr adds attr to event following the rules of slog.Handler.
+func appendAttr(event *zerolog.Event, attr slog.Attr) *zerolog.Event {
+	attr.Value = attr.Value.Resolve()
+	if attr.Value.Kind() != slog.KindGroup {
+		if attr.Key == "" && attr.Value.Kind() == slog.KindAny && attr.Value.Any() == nil {
+			return event
+		}
+		return appendResolvedAttr(event, attr)
+	}
+	for _, attr := range resolveAttrs([]slog.Attr{attr}) {
+		event = appendResolvedAttr(event, attr)
+	}
+	return event
+}
+
+func appendResolvedAttr(event *zerolog.Event, attr slog.Attr) *zerolog.Event {
 	switch attr.Value.Kind() {
 	case slog.KindBool:
 		event.Bool(attr.Key, attr.Value.Bool())
@@ -74,74 +325,22 @@ func addAttrToEvent(event *zerolog.Event, attr slog.Attr) *zerolog.Event {
 		event.Time(attr.Key, attr.Value.Time())
 	case slog.KindUint64:
 		event.Uint64(attr.Key, attr.Value.Uint64())
-	case slog.KindLogValuer:
-		event.Str(attr.Key, attr.Value.LogValuer().LogValue().String())
 	case slog.KindGroup:
 		child := zerolog.Dict()
 		for _, groupAttr := range attr.Value.Group() {
-			child = addAttrToEvent(child, groupAttr)
+			child = appendResolvedAttr(child, groupAttr)
 		}
 		event.Dict(attr.Key, child)
+	case slog.KindAny:
+		event = appendAny(event, attr.Key, attr.Value.Any(), 0, nil)
 	}
 	return event
 }
 
-func addAttrToContext(ctx zerolog.Context, attr slog.Attr) zerolog.Context {
-	switch attr.Value.Kind() {
-	case slog.KindBool:
-		return ctx.Bool(attr.Key, attr.Value.Bool())
-	case slog.KindDuration:
-		return ctx.Dur(attr.Key, attr.Value.Duration())
-	case slog.KindFloat64:
-		return ctx.Float64(attr.Key, attr.Value.Float64())
-	case slog.KindInt64:
-		return ctx.Int64(attr.Key, attr.Value.Int64())
-	case slog.KindString:
-		return ctx.Str(attr.Key, attr.Value.String())
-	case slog.KindTime:
-		return ctx.Time(attr.Key, attr.Value.Time())
-	case slog.KindUint64:
-		return ctx.Uint64(attr.Key, attr.Value.Uint64())
-	case slog.KindLogValuer:
-		return ctx.Str(attr.Key, attr.Value.LogValuer().LogValue().String())
-	case slog.KindGroup:
-		child := zerolog.Dict()
-		for _, groupAttr := range attr.Value.Group() {
-			child = addAttrToEvent(child, groupAttr)
-		}
-
-		return ctx.Dict(attr.Key, child)
-	}
-	return ctx
-}
-
-func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	childLogger := h.l.With()
-	for _, attr := range attrs {
-		childLogger = addAttrToContext(childLogger, attr)
-	}
-	l := childLogger.Logger()
-	return &Handler{l: &l, goa: h.goa}
-}
-
-func (h *Handler) WithGroup(name string) slog.Handler {
-	if h.goa == nil {
-		h.goa = new(withsupport.GroupOrAttrs)
-	}
-	return &Handler{l: h.l, goa: h.goa.WithGroup(name)}
-}
-
-func slogLevelToZerologLevel(level slog.Level) zerolog.Level {
-	switch level {
-	case slog.LevelDebug:
-		return zerolog.DebugLevel
-	case slog.LevelInfo:
-		return zerolog.InfoLevel
-	case slog.LevelWarn:
-		return zerolog.WarnLevel
-	case slog.LevelError:
-		return zerolog.ErrorLevel
-	default:
-		return zerolog.NoLevel
+// level returns the zerolog level of level.
+func (h *Handler) level(level slog.Level) zerolog.Level {
+	if l, ok := h.opts.Levels[level]; ok {
+		return l
 	}
+	return ZerologLevel(level)
 }
@@ -2,10 +2,20 @@ package xzerolog
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"io"
 	"testing"
+	"time"
 
+	"github.com/galecore/xslog"
+	"github.com/galecore/xslog/xdata"
+	"github.com/galecore/xslog/xslogtest"
+	"github.com/galecore/xslog/xtee"
+	"github.com/galecore/xslog/xtesting"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/exp/slog"
 )
 
@@ -136,3 +146,194 @@ func TestHandler_WithGroup(t *testing.T) {
 `
 	assert.Equal(t, expectedResult, buffer.String())
 }
+
+func TestHandler_Conformance(t *testing.T) {
+	xslogtest.Run(t, func(t *testing.T) (slog.Handler, func() []map[string]any) {
+		var buf xslogtest.Buffer
+		logger := zerolog.New(&buf).Level(zerolog.DebugLevel)
+		results := xslogtest.JSONLines(t, &buf)
+		return NewHandler(&logger), func() []map[string]any {
+			records := results()
+			for _, record := range records {
+				record[slog.MessageKey] = record[zerolog.MessageFieldName]
+				delete(record, zerolog.MessageFieldName)
+			}
+			return records
+		}
+	}, &xslogtest.Options{
+		// Handler writes zero times, which upstream users depend on.
+		Skip: []string{"zero-time"},
+	})
+}
+
+func TestHandlerWithOptions_Clock(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := zerolog.New(&buffer)
+	clock := xtesting.NewFakeClock(time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC), time.Second)
+	h := NewHandlerWithOptions(&logger, &HandlerOptions{Clock: clock}).WithAttrs([]slog.Attr{slog.String("k", "v")})
+	require.NoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "first", 0)))
+	require.NoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "second", 0)))
+	assert.Equal(t, `{"level":"info","k":"v","time":"2023-07-01T12:00:00Z","message":"first"}
+{"level":"info","k":"v","time":"2023-07-01T12:00:01Z","message":"second"}
+`, buffer.String())
+}
+
+func TestHandler_ContextClock(t *testing.T) {
+	start := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("records without time", func(t *testing.T) {
+		var buffer bytes.Buffer
+		logger := zerolog.New(&buffer)
+		ctx := xdata.WithClock(context.Background(), xtesting.NewFakeClock(start, time.Second))
+		override := xtesting.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Second)
+
+		require.NoError(t, NewHandler(&logger).Handle(ctx, slog.NewRecord(time.Time{}, slog.LevelInfo, "bound", 0)))
+		require.NoError(t, NewHandlerWithOptions(&logger, &HandlerOptions{Clock: override}).
+			Handle(ctx, slog.NewRecord(time.Time{}, slog.LevelInfo, "override", 0)))
+		assert.Equal(t, `{"level":"info","time":"2023-07-01T12:00:00Z","message":"bound"}
+{"level":"info","time":"2024-01-01T00:00:00Z","message":"override"}
+`, buffer.String())
+	})
+
+	t.Run("records with time", func(t *testing.T) {
+		var first, second bytes.Buffer
+		firstLogger, secondLogger := zerolog.New(&first), zerolog.New(&second)
+		clock := xtesting.NewFakeClock(start, time.Second)
+		ctx := xdata.WithClock(context.Background(), clock)
+		ctx = xslog.WithLogger(ctx, slog.New(xtee.NewHandler(NewHandler(&firstLogger), NewHandler(&secondLogger))))
+
+		xslog.Info(ctx, "test")
+		want := `{"level":"info","time":"2023-07-01T12:00:00Z","message":"test"}` + "\n"
+		assert.Equal(t, want, first.String())
+		assert.Equal(t, want, second.String())
+		assert.Equal(t, start.Add(time.Second), clock.Now(), "the clock is read once per record")
+	})
+}
+
+func TestHandler_NestedGroups(t *testing.T) {
+	type build func(l *slog.Logger) *slog.Logger
+	tests := []struct {
+		name  string
+		build build
+		args  []any
+	}{
+		{
+			name: "attrs between groups",
+			build: func(l *slog.Logger) *slog.Logger {
+				return l.With("a", 1).WithGroup("g").With("b", 2).WithGroup("h").With("c", 3)
+			},
+			args: []any{"d", 4},
+		},
+		{
+			name: "groups in records",
+			build: func(l *slog.Logger) *slog.Logger {
+				return l.WithGroup("g").WithGroup("h").With(slog.Group("", slog.String("inlined", "x")))
+			},
+			args: []any{slog.Group("r", slog.Int("n", 1), slog.Group("empty")), slog.Group("", "e", "f")},
+		},
+		{
+			name: "quoted group names",
+			build: func(l *slog.Logger) *slog.Logger {
+				return l.WithGroup(`a "quoted" name`).With("k", "v")
+			},
+			args: []any{"n", 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want, got bytes.Buffer
+			tt.build(slog.New(slog.NewJSONHandler(&want, nil))).Info("test", tt.args...)
+			logger := zerolog.New(&got)
+			tt.build(slog.New(NewHandler(&logger))).Info("test", tt.args...)
+
+			var wantJSON, gotJSON map[string]any
+			require.NoError(t, json.Unmarshal(want.Bytes(), &wantJSON))
+			require.NoError(t, json.Unmarshal(got.Bytes(), &gotJSON))
+			for _, key := range []string{slog.TimeKey, slog.LevelKey, slog.MessageKey} {
+				delete(wantJSON, key)
+			}
+			for _, key := range []string{zerolog.TimestampFieldName, zerolog.LevelFieldName, zerolog.MessageFieldName} {
+				delete(gotJSON, key)
+			}
+			assert.Equal(t, wantJSON, gotJSON, got.String())
+		})
+	}
+
+	t.Run("empty inner group", func(t *testing.T) {
+		// Unlike slog.JSONHandler of golang.org/x/exp, Handler drops groups that end up empty.
+		var buffer bytes.Buffer
+		logger := zerolog.New(&buffer)
+		slog.New(NewHandler(&logger)).WithGroup("g").With("b", 2).WithGroup("h").WithGroup("i").Info("test")
+		assert.Contains(t, buffer.String(), `{"level":"info","g":{"b":2},"time":`)
+	})
+}
+
+func BenchmarkHandler_Groups(b *testing.B) {
+	zl := zerolog.New(io.Discard)
+	logger := slog.New(NewHandler(&zl)).With("service", "api").WithGroup("http").With("method", "GET").WithGroup("response")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("request", "status", 200, "bytes", 1024)
+	}
+}
+
+func TestZerologLevel(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  zerolog.Level
+	}{
+		{level: slog.LevelDebug - 8, want: zerolog.TraceLevel},
+		{level: slog.LevelDebug - 4, want: zerolog.TraceLevel},
+		{level: slog.LevelDebug, want: zerolog.DebugLevel},
+		{level: slog.LevelDebug + 1, want: zerolog.DebugLevel},
+		{level: slog.LevelInfo, want: zerolog.InfoLevel},
+		{level: slog.LevelInfo + 2, want: zerolog.InfoLevel},
+		{level: slog.LevelWarn, want: zerolog.WarnLevel},
+		{level: slog.LevelError, want: zerolog.ErrorLevel},
+		{level: slog.LevelError + 3, want: zerolog.ErrorLevel},
+		{level: slog.LevelError + 4, want: zerolog.FatalLevel},
+		{level: slog.LevelError + 8, want: zerolog.PanicLevel},
+		{level: slog.LevelError + 100, want: zerolog.PanicLevel},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ZerologLevel(tt.level), tt.level.String())
+	}
+}
+
+func TestHandlerWithOptions_Levels(t *testing.T) {
+	t.Run("filtering", func(t *testing.T) {
+		var buffer bytes.Buffer
+		logger := zerolog.New(&buffer).Level(zerolog.InfoLevel)
+		l := slog.New(NewHandler(&logger))
+		l.Log(context.Background(), slog.LevelDebug-4, "trace")
+		l.Log(context.Background(), slog.LevelInfo+2, "notice")
+		assert.Equal(t, `{"level":"info","time":"`, buffer.String()[:len(`{"level":"info","time":"`)])
+		assert.NotContains(t, buffer.String(), "trace")
+	})
+
+	t.Run("fatal and panic do not exit", func(t *testing.T) {
+		var buffer bytes.Buffer
+		logger := zerolog.New(&buffer)
+		l := slog.New(NewHandler(&logger))
+		assert.NotPanics(t, func() {
+			l.Log(context.Background(), slog.LevelError+4, "fatal")
+			l.Log(context.Background(), slog.LevelError+8, "panic")
+		})
+		assert.Contains(t, buffer.String(), `{"level":"fatal",`)
+		assert.Contains(t, buffer.String(), `{"level":"panic",`)
+	})
+
+	t.Run("overrides and slog level", func(t *testing.T) {
+		var buffer bytes.Buffer
+		logger := zerolog.New(&buffer)
+		l := slog.New(NewHandlerWithOptions(&logger, &HandlerOptions{
+			Levels:       map[slog.Level]zerolog.Level{slog.LevelInfo + 2: zerolog.WarnLevel},
+			AddSlogLevel: true,
+		})).With("k", "v")
+		l.Log(context.Background(), slog.LevelInfo+2, "notice")
+		l.Info("info")
+		assert.Contains(t, buffer.String(), `{"level":"warn","k":"v","slog_level":2,`)
+		assert.Contains(t, buffer.String(), `{"level":"info","k":"v","slog_level":0,`)
+	})
+}
@@ -0,0 +1,195 @@
+package xzerolog
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/exp/slog"
+)
+
+// MaxDepth is the maximum nesting of slices, maps and pointers that Handler follows within a slog.KindAny value.
+// Deeper values are written as "<max depth>", and values that contain themselves are cut off with "<cycle>".
+const MaxDepth = 16
+
+const (
+	maxDepthValue = "<max depth>"
+	cycleValue    = "<cycle>"
+)
+
+var nullJSON = []byte("null")
+
+// appendAny adds v to event under key. LogValuers are resolved, errors, byte slices and marshalers are written
+// the way zerolog writes them, and other values are written by reflection. seen lists the addresses of the
+// pointers, slices and maps that contain v.
+func appendAny(event *zerolog.Event, key string, v any, depth int, seen []uintptr) *zerolog.Event {
+	if depth > MaxDepth {
+		return event.Str(key, maxDepthValue)
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		// Methods of nil pointers may panic.
+		return event.RawJSON(key, nullJSON)
+	}
+	switch v := v.(type) {
+	case nil:
+		return event.RawJSON(key, nullJSON)
+	case slog.Value:
+		return appendAttr(event, slog.Attr{Key: key, Value: v})
+	case time.Time:
+		return event.Time(key, v)
+	case time.Duration:
+		return event.Dur(key, v)
+	case slog.LogValuer:
+		value := slog.AnyValue(v).Resolve()
+		if value.Kind() == slog.KindAny {
+			if _, ok := value.Any().(slog.LogValuer); ok {
+				// Resolve gave up on a chain of LogValuers.
+				return event.Str(key, fmt.Sprint(value.Any()))
+			}
+			return appendAny(event, key, value.Any(), depth+1, seen)
+		}
+		return appendAttr(event, slog.Attr{Key: key, Value: value})
+	case error:
+		if key == zerolog.ErrorFieldName {
+			return event.Err(v)
+		}
+		return event.AnErr(key, v)
+	case []byte:
+		return event.Bytes(key, v)
+	case json.Marshaler:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return event.Str(key, fmt.Sprintf("marshaling error: %v", err))
+		}
+		return event.RawJSON(key, b)
+	case encoding.TextMarshaler:
+		b, err := v.MarshalText()
+		if err != nil {
+			return event.Str(key, fmt.Sprintf("marshaling error: %v", err))
+		}
+		return event.Str(key, string(b))
+	case fmt.Stringer:
+		return event.Stringer(key, v)
+	case []string:
+		return event.Strs(key, v)
+	case []error:
+		return event.Errs(key, v)
+	}
+	return appendReflect(event, key, reflect.ValueOf(v), depth, seen)
+}
+
+func appendReflect(event *zerolog.Event, key string, rv reflect.Value, depth int, seen []uintptr) *zerolog.Event {
+	switch rv.Kind() {
+	case reflect.Bool:
+		return event.Bool(key, rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return event.Int64(key, rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return event.Uint64(key, rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return event.Float64(key, rv.Float())
+	case reflect.String:
+		return event.Str(key, rv.String())
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return event.RawJSON(key, nullJSON)
+		}
+		if rv.Kind() == reflect.Pointer {
+			if containsAddr(seen, rv.Pointer()) {
+				return event.Str(key, cycleValue)
+			}
+			seen = append(seen[:len(seen):len(seen)], rv.Pointer())
+		}
+		return appendAny(event, key, rv.Elem().Interface(), depth+1, seen)
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice {
+			if rv.IsNil() {
+				return event.RawJSON(key, nullJSON)
+			}
+			if rv.Len() > 0 {
+				if containsAddr(seen, rv.Pointer()) {
+					return event.Str(key, cycleValue)
+				}
+				seen = append(seen[:len(seen):len(seen)], rv.Pointer())
+			}
+		}
+		arr := zerolog.Arr()
+		for i := 0; i < rv.Len(); i++ {
+			arr = appendArrayElem(arr, rv.Index(i).Interface(), depth+1, seen)
+		}
+		return event.Array(key, arr)
+	case reflect.Map:
+		if rv.IsNil() {
+			return event.RawJSON(key, nullJSON)
+		}
+		if containsAddr(seen, rv.Pointer()) {
+			return event.Str(key, cycleValue)
+		}
+		seen = append(seen[:len(seen):len(seen)], rv.Pointer())
+		keys := rv.MapKeys()
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = fmt.Sprint(k.Interface())
+		}
+		order := make([]int, len(keys))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(i, j int) bool { return names[order[i]] < names[order[j]] })
+		dict := zerolog.Dict()
+		for _, i := range order {
+			dict = appendAny(dict, names[i], rv.MapIndex(keys[i]).Interface(), depth+1, seen)
+		}
+		return event.Dict(key, dict)
+	case reflect.Struct:
+		// encoding/json honors field tags and reports cycles as errors.
+		return event.Interface(key, rv.Interface())
+	default:
+		return event.Str(key, fmt.Sprintf("%+v", rv.Interface()))
+	}
+}
+
+// appendArrayElem appends v to arr like appendAny.
+func appendArrayElem(arr *zerolog.Array, v any, depth int, seen []uintptr) *zerolog.Array {
+	switch v := v.(type) {
+	case nil:
+		return arr.RawJSON(nullJSON)
+	case string:
+		return arr.Str(v)
+	case bool:
+		return arr.Bool(v)
+	case int:
+		return arr.Int(v)
+	case int64:
+		return arr.Int64(v)
+	case uint64:
+		return arr.Uint64(v)
+	case float64:
+		return arr.Float64(v)
+	case time.Time:
+		return arr.Time(v)
+	case time.Duration:
+		return arr.Dur(v)
+	}
+	value := renderField(func(event *zerolog.Event, key string) *zerolog.Event {
+		return appendAny(event, key, v, depth, seen)
+	})
+	if value == nil {
+		// v is a LogValuer that resolved to an empty group.
+		return arr.RawJSON(nullJSON)
+	}
+	return arr.RawJSON(value)
+}
+
+func containsAddr(seen []uintptr, addr uintptr) bool {
+	for _, s := range seen {
+		if s == addr {
+			return true
+		}
+	}
+	return false
+}
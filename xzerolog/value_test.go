@@ -0,0 +1,98 @@
+package xzerolog
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type token string
+
+func (token) LogValue() slog.Value {
+	return slog.StringValue("REDACTED")
+}
+
+type account struct {
+	id int
+}
+
+func (a account) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("id", a.id), slog.Any("token", token("secret")))
+}
+
+type status int
+
+func (s *status) String() string {
+	return [...]string{"ok", "failed"}[*s]
+}
+
+func TestHandler_AnyValues(t *testing.T) {
+	cyclic := map[string]any{"name": "root"}
+	cyclic["self"] = cyclic
+	var nilStatus *status
+
+	deep := any("bottom")
+	for i := 0; i < MaxDepth+2; i++ {
+		deep = []any{deep}
+	}
+
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "error", value: errors.New("boom"), want: `"v":"boom"`},
+		{name: "bytes", value: []byte("raw"), want: `"v":"raw"`},
+		{name: "struct", value: user{ID: 1, Name: "gopher"}, want: `"v":{"id":1,"name":"gopher"}`},
+		{name: "pointer", value: &user{ID: 2}, want: `"v":{"id":2,"name":""}`},
+		{name: "nil pointer", value: (*user)(nil), want: `"v":null`},
+		{name: "nil stringer", value: nilStatus, want: `"v":null`},
+		{name: "stringer", value: new(status), want: `"v":"ok"`},
+		{name: "text marshaler", value: net.IPv4(127, 0, 0, 1), want: `"v":"127.0.0.1"`},
+		{name: "ints", value: []int{1, 2, 3}, want: `"v":[1,2,3]`},
+		{name: "strings", value: []string{"a", "b"}, want: `"v":["a","b"]`},
+		{name: "mixed", value: []any{"a", 1, nil, true, 1.5, []int{2}}, want: `"v":["a",1,null,true,1.5,[2]]`},
+		{name: "errors", value: []error{errors.New("a"), errors.New("b")}, want: `"v":["a","b"]`},
+		{name: "nil slice", value: []int(nil), want: `"v":null`},
+		{name: "map", value: map[string]any{"b": 2, "a": []string{"x"}}, want: `"v":{"a":["x"],"b":2}`},
+		{name: "int keys", value: map[int]string{2: "b", 1: "a"}, want: `"v":{"1":"a","2":"b"}`},
+		{name: "log valuers", value: []any{token("secret"), account{id: 7}}, want: `"v":["REDACTED",{"id":7,"token":"REDACTED"}]`},
+		{name: "log valuer in map", value: map[string]any{"account": account{id: 7}}, want: `"v":{"account":{"id":7,"token":"REDACTED"}}`},
+		{name: "times", value: []time.Duration{time.Second}, want: `"v":[1000]`},
+		{name: "cycle", value: cyclic, want: `"v":{"name":"root","self":"<cycle>"}`},
+		{name: "max depth", value: deep, want: `"<max depth>"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			logger := zerolog.New(&buffer)
+			slog.New(NewHandler(&logger)).Info("test", "v", tt.value)
+			assert.Contains(t, buffer.String(), tt.want)
+		})
+	}
+}
+
+func TestHandler_AnyValuesInGroups(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := zerolog.New(&buffer)
+	slog.New(NewHandler(&logger)).
+		With("error", errors.New("bound")).
+		WithGroup("g").
+		With("account", account{id: 1}).
+		Error("test", slog.Any("error", errors.New("failed")), "tags", []string{"a"})
+	assert.True(t, strings.HasPrefix(buffer.String(),
+		`{"level":"error","error":"bound","g":{"account":{"id":1,"token":"REDACTED"},"error":"failed","tags":["a"]},`),
+		buffer.String())
+}